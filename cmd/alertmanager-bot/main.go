@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"runtime"
 	"strings"
 	"syscall"
@@ -54,6 +55,8 @@ var (
 var cli struct {
 	AlertmanagerURL *url.URL `name:"alertmanager.url" default:"http://localhost:9093/" help:"The URL that's used to connect to the alertmanager"`
 	ListenAddr      string   `name:"listen.addr" default:"0.0.0.0:8080" help:"The address the alertmanager-bot listens on for incoming webhooks"`
+	ExternalURL     *url.URL `name:"listen.externalURL" help:"The URL the Alertmanager sends webhooks to, if the alertmanager-bot is behind a reverse proxy"`
+	AuditToken      string   `name:"audit.token" env:"AUDIT_TOKEN" help:"Serve the audit log on /api/audit to requests with this bearer token, it isn't served without a token"`
 	LogJSON         bool     `name:"log.json" default:"false" help:"Tell the application to log json and not key value pairs"`
 	LogLevel        string   `name:"log.level" default:"info" enum:"error,warn,info,debug" help:"The log level to use for filtering logs"`
 	TemplatePaths   []string `name:"template.paths" default:"/templates/default.tmpl" help:"The paths to the template"`
@@ -85,8 +88,29 @@ type cliEtcd struct {
 }
 
 type cliTelegram struct {
-	Admins []int  `required:"true" name:"telegram.admin" help:"The ID of the initial Telegram Admin"`
-	Token  string `required:"true" name:"telegram.token" env:"TELEGRAM_TOKEN" help:"The token used to connect with Telegram"`
+	Admins []int    `required:"true" name:"telegram.admin" help:"The ID of the initial Telegram Admin"`
+	Token  string   `required:"true" name:"telegram.token" env:"TELEGRAM_TOKEN" help:"The token used to connect with Telegram"`
+	Topics []string `name:"telegram.topic" help:"The name of a topic chats can subscribe to"`
+
+	MembershipTTL        time.Duration `name:"telegram.membershipTTL" default:"10m" help:"How long to cache that a user is a member of a trusted group chat"`
+	AckReminder          time.Duration `name:"telegram.ackReminder" default:"30m" help:"Remind chats of firing alerts nobody acknowledged after this duration, 0 to disable"`
+	NotificationMaxAge   time.Duration `name:"telegram.notificationMaxAge" default:"24h" help:"Forget firing alerts without a webhook for this duration, longer than the Alertmanager's repeat_interval, 0 to disable"`
+	SilenceReminder      time.Duration `name:"telegram.silenceReminder" default:"15m" help:"Remind of silences this long before they expire and notify about silences created elsewhere, 0 to disable"`
+	SilenceWarnThreshold int           `name:"telegram.silenceWarnThreshold" default:"10" help:"Warn before creating silences that silence more than this many alerts, 0 to disable"`
+	HistoryRetention     time.Duration `name:"telegram.historyRetention" default:"720h" help:"Keep the alert history this long, 0 to keep it forever"`
+	AuditRetention       time.Duration `name:"telegram.auditRetention" default:"720h" help:"Keep the audit log this long, 0 to keep it forever"`
+	FlapThreshold        int           `name:"telegram.flapThreshold" default:"0" help:"Collapse notifications of alert groups changing between firing and resolved more often than this within the flap window, 0 to disable"`
+	FlapWindow           time.Duration `name:"telegram.flapWindow" default:"30m" help:"The window to detect flapping alert groups in"`
+	DedupWindow          time.Duration `name:"telegram.dedupWindow" default:"0" help:"Drop webhooks with the same alerts and statuses as the last one of their alert group within this duration, 0 to disable"`
+	WatchdogAlert        string        `name:"telegram.watchdogAlert" default:"Watchdog" help:"The name of the always firing alert expected on /webhooks/watchdog"`
+	WatchdogTimeout      time.Duration `name:"telegram.watchdogTimeout" default:"0" help:"Tell the admins when the watchdog alert didn't arrive for this duration, 0 to disable"`
+	Escalations          string        `name:"telegram.escalations" type:"path" help:"Path to a YAML file with escalation policies for unacknowledged alerts"`
+}
+
+// storeKeyPrefix returns the key prefix for the given kind of data,
+// next to the chats stored under the configured StorePrefix, with or without a trailing slash.
+func storeKeyPrefix(name string) string {
+	return path.Join(path.Dir(strings.TrimSuffix(cli.StorePrefix, "/")), name)
 }
 
 func main() {
@@ -188,6 +212,12 @@ func main() {
 	}
 	defer kvStore.Close()
 
+	audit, err := telegram.NewAuditStore(kvStore, storeKeyPrefix("audit"))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create audit store", "err", err)
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// TODO Needs fan out for multiple bots
@@ -207,22 +237,111 @@ func main() {
 			commandCounter.WithLabelValues(command).Inc()
 		}
 
+		duplicatesCounter := prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanagerbot_webhooks_duplicate_total",
+			Help: "Number of duplicate webhooks dropped by this bot",
+		})
+		reg.MustRegister(duplicatesCounter)
+
 		chats, err := telegram.NewChatStore(kvStore, cli.StorePrefix)
 		if err != nil {
 			level.Error(logger).Log("msg", "failed to create chat store", "err", err)
 			os.Exit(1)
 		}
 
+		topics, err := telegram.NewTopicStore(kvStore, storeKeyPrefix("topics"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create topic store", "err", err)
+			os.Exit(1)
+		}
+
+		roles, err := telegram.NewRoleStore(kvStore, storeKeyPrefix("roles"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create role store", "err", err)
+			os.Exit(1)
+		}
+
+		trusted, err := telegram.NewRoleStore(kvStore, storeKeyPrefix("trusted"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create trusted chat store", "err", err)
+			os.Exit(1)
+		}
+
+		notifications, err := telegram.NewNotificationStore(kvStore, storeKeyPrefix("notifications"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create notification store", "err", err)
+			os.Exit(1)
+		}
+
+		rotations, err := telegram.NewRotationStore(kvStore, storeKeyPrefix("rotations"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create rotation store", "err", err)
+			os.Exit(1)
+		}
+
+		receivers, err := telegram.NewReceiverStore(kvStore, storeKeyPrefix("receivers"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create receiver store", "err", err)
+			os.Exit(1)
+		}
+
+		silences, err := telegram.NewSilenceStore(kvStore, storeKeyPrefix("silences"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create silence store", "err", err)
+			os.Exit(1)
+		}
+
+		maintenance, err := telegram.NewMaintenanceStore(kvStore, storeKeyPrefix("maintenance"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create maintenance store", "err", err)
+			os.Exit(1)
+		}
+
+		history, err := telegram.NewHistoryStore(kvStore, storeKeyPrefix("history"))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create history store", "err", err)
+			os.Exit(1)
+		}
+
+		var escalations []telegram.EscalationPolicy
+		if cli.cliTelegram.Escalations != "" {
+			escalations, err = telegram.LoadEscalationPolicies(cli.cliTelegram.Escalations)
+			if err != nil {
+				level.Error(logger).Log("msg", "failed to load escalation policies", "err", err)
+				os.Exit(1)
+			}
+		}
+
 		bot, err := telegram.NewBot(
 			chats, cli.cliTelegram.Token, cli.cliTelegram.Admins[0],
 			telegram.WithLogger(tlogger),
 			telegram.WithCommandEvent(commandCount),
+			telegram.WithDuplicateEvent(duplicatesCounter.Inc),
 			telegram.WithAddr(cli.ListenAddr),
 			telegram.WithAlertmanager(am),
 			telegram.WithTemplates(cli.AlertmanagerURL, cli.TemplatePaths...),
 			telegram.WithRevision(Revision),
 			telegram.WithStartTime(StartTime),
 			telegram.WithExtraAdmins(cli.cliTelegram.Admins[1:]...),
+			telegram.WithTopics(topics, cli.cliTelegram.Topics...),
+			telegram.WithRoles(roles),
+			telegram.WithTrustedChats(trusted, cli.cliTelegram.MembershipTTL),
+			telegram.WithAuditLog(audit, cli.cliTelegram.AuditRetention),
+			telegram.WithRotations(rotations),
+			telegram.WithReceivers(receivers),
+			telegram.WithExternalURL(cli.ExternalURL),
+			telegram.WithNotifications(notifications),
+			telegram.WithAckReminder(cli.cliTelegram.AckReminder),
+			telegram.WithNotificationMaxAge(cli.cliTelegram.NotificationMaxAge),
+			telegram.WithEscalationPolicies(escalations...),
+			telegram.WithSilences(silences),
+			telegram.WithSilenceReminder(cli.cliTelegram.SilenceReminder),
+			telegram.WithSilenceWarnThreshold(cli.cliTelegram.SilenceWarnThreshold),
+			telegram.WithMaintenance(maintenance),
+			telegram.WithHistory(history, cli.cliTelegram.HistoryRetention),
+			telegram.WithFlapDetection(cli.cliTelegram.FlapThreshold, cli.cliTelegram.FlapWindow),
+			telegram.WithDeduplication(cli.cliTelegram.DedupWindow),
+			telegram.WithWatchdog(cli.cliTelegram.WatchdogAlert, cli.cliTelegram.WatchdogTimeout),
 		)
 		if err != nil {
 			level.Error(tlogger).Log("msg", "failed to create bot", "err", err)
@@ -260,6 +379,12 @@ func main() {
 
 		m := http.NewServeMux()
 		m.HandleFunc("/webhooks/telegram/", alertmanager.HandleTelegramWebhook(wlogger, webhooksCounter, webhooks))
+		m.HandleFunc("/webhooks/topic/", alertmanager.HandleTopicWebhook(wlogger, webhooksCounter, webhooks))
+		m.HandleFunc("/webhooks/oncall/", alertmanager.HandleOnCallWebhook(wlogger, webhooksCounter, webhooks))
+		m.HandleFunc("/webhooks/watchdog", alertmanager.HandleWatchdogWebhook(wlogger, webhooksCounter, webhooks))
+		if cli.AuditToken != "" {
+			m.HandleFunc("/api/audit", telegram.HandleAudit(audit, cli.AuditToken))
+		}
 		m.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
 		m.HandleFunc("/health", handleHealth)
 		m.HandleFunc("/healthz", handleHealth)
@@ -277,7 +402,7 @@ func main() {
 		})
 	}
 	{
-		sig := make(chan os.Signal)
+		sig := make(chan os.Signal, 1)
 		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 
 		g.Add(func() error {
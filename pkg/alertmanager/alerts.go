@@ -5,51 +5,162 @@ import (
 	"time"
 
 	"github.com/prometheus/alertmanager/api/v2/client/alert"
+	"github.com/prometheus/alertmanager/api/v2/client/alertgroup"
+	"github.com/prometheus/alertmanager/api/v2/models"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
 )
 
-func (c *Client) ListAlerts(ctx context.Context, receiver string, silenced bool) ([]*types.Alert, error) {
-	getAlerts, err := c.alertmanager.Alert.GetAlerts(alert.NewGetAlertsParams().WithContext(ctx).
-		WithReceiver(&receiver).
-		WithSilenced(&silenced),
-	)
+// Alert is an alert as returned by the Alertmanager API,
+// including the fields types.Alert has no place for.
+type Alert struct {
+	types.Alert
+	Fingerprint string
+	Status      types.AlertStatus
+	Receivers   []string
+}
+
+// AlertFilter selects the alerts to list.
+type AlertFilter struct {
+	// Receiver of the alerts, alerts of all receivers if empty.
+	Receiver string
+	// Matchers the alerts' labels need to match, like severity=critical or job=~api.*.
+	Matchers []string
+
+	// Active, Silenced, Inhibited and Unprocessed include alerts in these states.
+	Active      bool
+	Silenced    bool
+	Inhibited   bool
+	Unprocessed bool
+}
+
+// ListAlerts returns the alerts selected by the filter.
+func (c *Client) ListAlerts(ctx context.Context, filter AlertFilter) ([]*Alert, error) {
+	params := alert.NewGetAlertsParams().WithContext(ctx).
+		WithActive(&filter.Active).
+		WithSilenced(&filter.Silenced).
+		WithInhibited(&filter.Inhibited).
+		WithUnprocessed(&filter.Unprocessed).
+		WithFilter(filter.Matchers)
+	if filter.Receiver != "" {
+		params = params.WithReceiver(&filter.Receiver)
+	}
+
+	getAlerts, err := c.alertmanager.Alert.GetAlerts(params)
 	if err != nil {
 		return nil, err
 	}
 
-	alerts := make([]*types.Alert, 0, len(getAlerts.Payload))
+	alerts := make([]*Alert, 0, len(getAlerts.Payload))
 	for _, a := range getAlerts.Payload {
-		labels := make(model.LabelSet, len(a.Labels))
-		for name, value := range a.Labels {
-			labels[model.LabelName(name)] = model.LabelValue(value)
+		alert := alertFromAPI(a)
+		// The Alertmanager doesn't filter unprocessed alerts itself.
+		if !filter.Unprocessed && alert.Status.State == types.AlertStateUnprocessed {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// AlertGroup is a group of alerts as the Alertmanager notifies a receiver about them.
+type AlertGroup struct {
+	Labels   model.LabelSet
+	Receiver string
+	Alerts   []*Alert
+}
+
+// ListAlertGroups returns the alert groups with alerts selected by the filter.
+func (c *Client) ListAlertGroups(ctx context.Context, filter AlertFilter) ([]*AlertGroup, error) {
+	params := alertgroup.NewGetAlertGroupsParams().WithContext(ctx).
+		WithActive(&filter.Active).
+		WithSilenced(&filter.Silenced).
+		WithInhibited(&filter.Inhibited).
+		WithFilter(filter.Matchers)
+	if filter.Receiver != "" {
+		params = params.WithReceiver(&filter.Receiver)
+	}
+
+	getGroups, err := c.alertmanager.Alertgroup.GetAlertGroups(params)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*AlertGroup, 0, len(getGroups.Payload))
+	for _, g := range getGroups.Payload {
+		group := &AlertGroup{Labels: labelSet(g.Labels)}
+		if g.Receiver != nil && g.Receiver.Name != nil {
+			group.Receiver = *g.Receiver.Name
 		}
-		annotations := make(model.LabelSet, len(a.Annotations))
-		for name, value := range a.Annotations {
-			annotations[model.LabelName(name)] = model.LabelValue(value)
+		for _, a := range g.Alerts {
+			alert := alertFromAPI(a)
+			if !filter.Unprocessed && alert.Status.State == types.AlertStateUnprocessed {
+				continue
+			}
+			group.Alerts = append(group.Alerts, alert)
 		}
+		if len(group.Alerts) == 0 {
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func labelSet(ls models.LabelSet) model.LabelSet {
+	labels := make(model.LabelSet, len(ls))
+	for name, value := range ls {
+		labels[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return labels
+}
 
-		endsAt := time.Time{}
-		if a.EndsAt != nil {
-			endsAt = time.Time(*a.EndsAt)
+// alertFromAPI converts an alert returned by the API.
+func alertFromAPI(a *models.GettableAlert) *Alert {
+	endsAt := time.Time{}
+	if a.EndsAt != nil {
+		endsAt = time.Time(*a.EndsAt)
+	}
+	updatedAt := time.Time{}
+	if a.UpdatedAt != nil {
+		updatedAt = time.Time(*a.UpdatedAt)
+	}
+
+	var status types.AlertStatus
+	if a.Status != nil {
+		status.SilencedBy = a.Status.SilencedBy
+		status.InhibitedBy = a.Status.InhibitedBy
+		if a.Status.State != nil {
+			status.State = types.AlertState(*a.Status.State)
 		}
-		updatedAt := time.Time{}
-		if a.UpdatedAt != nil {
-			updatedAt = time.Time(*a.UpdatedAt)
+	}
+	receivers := make([]string, 0, len(a.Receivers))
+	for _, r := range a.Receivers {
+		if r.Name != nil {
+			receivers = append(receivers, *r.Name)
 		}
+	}
+	fingerprint := ""
+	if a.Fingerprint != nil {
+		fingerprint = *a.Fingerprint
+	}
 
-		alerts = append(alerts, &types.Alert{
+	return &Alert{
+		Alert: types.Alert{
 			Alert: model.Alert{
-				Labels:       labels,
-				Annotations:  annotations,
+				Labels:       labelSet(a.Labels),
+				Annotations:  labelSet(a.Annotations),
 				StartsAt:     time.Time(*a.StartsAt),
 				EndsAt:       endsAt,
 				GeneratorURL: a.GeneratorURL.String(),
 			},
 			UpdatedAt: updatedAt,
 			Timeout:   false,
-		})
+		},
+		Fingerprint: fingerprint,
+		Status:      status,
+		Receivers:   receivers,
 	}
-
-	return alerts, nil
 }
@@ -2,6 +2,7 @@ package alertmanager
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/go-openapi/strfmt"
 	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/pkg/labels"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
@@ -157,25 +159,34 @@ func TestClient(t *testing.T) {
 		require.Equal(t, expected, status)
 	}
 	{
-		expected := []*types.Alert{{
-			Alert: model.Alert{
-				Labels: model.LabelSet{
-					model.LabelName("alertname"):  model.LabelValue("Watchdog"),
-					model.LabelName("prometheus"): model.LabelValue("monitoring/k8s"),
-					model.LabelName("severity"):   model.LabelValue("none"),
+		expected := []*Alert{{
+			Alert: types.Alert{
+				Alert: model.Alert{
+					Labels: model.LabelSet{
+						model.LabelName("alertname"):  model.LabelValue("Watchdog"),
+						model.LabelName("prometheus"): model.LabelValue("monitoring/k8s"),
+						model.LabelName("severity"):   model.LabelValue("none"),
+					},
+					Annotations: model.LabelSet{
+						model.LabelName("message"): model.LabelValue("This is an alert meant to ensure that the entire alerting pipeline is functional."),
+					},
+					StartsAt:     time.Date(2021, 01, 27, 16, 56, 37, 0, time.UTC),
+					EndsAt:       time.Date(2021, 02, 22, 0, 52, 37, 0, time.UTC),
+					GeneratorURL: "https://prometheus.io/graph?g0.expr=vector%281%29&g0.tab=1",
 				},
-				Annotations: model.LabelSet{
-					model.LabelName("message"): model.LabelValue("This is an alert meant to ensure that the entire alerting pipeline is functional."),
-				},
-				StartsAt:     time.Date(2021, 01, 27, 16, 56, 37, 0, time.UTC),
-				EndsAt:       time.Date(2021, 02, 22, 0, 52, 37, 0, time.UTC),
-				GeneratorURL: "https://prometheus.io/graph?g0.expr=vector%281%29&g0.tab=1",
+				UpdatedAt: time.Date(2021, 02, 22, 0, 48, 37, 0, time.UTC),
+				Timeout:   false,
+			},
+			Fingerprint: "7a90bbdd1d39f61b",
+			Status: types.AlertStatus{
+				State:       types.AlertStateActive,
+				SilencedBy:  []string{},
+				InhibitedBy: []string{},
 			},
-			UpdatedAt: time.Date(2021, 02, 22, 0, 48, 37, 0, time.UTC),
-			Timeout:   false,
+			Receivers: []string{"healthcheck"},
 		}}
 
-		alerts, err := client.ListAlerts(context.Background(), "", false)
+		alerts, err := client.ListAlerts(context.Background(), AlertFilter{Active: true})
 		require.NoError(t, err)
 		require.Equal(t, expected, alerts)
 	}
@@ -187,14 +198,213 @@ func TestClient(t *testing.T) {
 			StartsAt:  time.Date(2021, 01, 11, 16, 10, 11, 0, time.UTC),
 			EndsAt:    time.Date(2022, 01, 11, 16, 10, 02, 0, time.UTC),
 			UpdatedAt: time.Date(2021, 01, 11, 16, 10, 11, 0, time.UTC),
-			Matchers:  types.Matchers{},
+			Matchers: labels.Matchers{
+				{Type: labels.MatchEqual, Name: "alertname", Value: "KubeMemoryOvercommit"},
+				{Type: labels.MatchEqual, Name: "prometheus", Value: "monitoring/metalmatze"},
+				{Type: labels.MatchEqual, Name: "severity", Value: "warning"},
+			},
 			Status: types.SilenceStatus{
 				State: types.SilenceStateActive,
 			},
 		}}
 
-		alerts, err := client.ListSilences(context.Background())
+		alerts, err := client.ListSilences(context.Background(), SilenceFilter{})
 		require.NoError(t, err)
 		require.Equal(t, expected, alerts)
 	}
 }
+
+func TestCreateSilence(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v2/silences", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+
+		var s models.PostableSilence
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&s))
+		require.Equal(t, "elliot", *s.CreatedBy)
+		require.Len(t, s.Matchers, 1)
+		require.Equal(t, "alertname", *s.Matchers[0].Name)
+		require.Equal(t, "Watch.*", *s.Matchers[0].Value)
+		require.True(t, *s.Matchers[0].IsRegex)
+		require.False(t, *s.Matchers[0].IsEqual)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"silenceID":"34f5f82b-b66f-456b-aff7-b556a7eafe81"}`))
+	})
+
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	id, err := client.CreateSilence(context.Background(), &types.Silence{
+		Matchers:  labels.Matchers{{Type: labels.MatchNotRegexp, Name: "alertname", Value: "Watch.*"}},
+		StartsAt:  time.Now(),
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "elliot",
+		Comment:   "test",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "34f5f82b-b66f-456b-aff7-b556a7eafe81", id)
+}
+
+func TestExpireSilence(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v2/silence/34f5f82b-b66f-456b-aff7-b556a7eafe81", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+	})
+
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	require.NoError(t, client.ExpireSilence(context.Background(), "34f5f82b-b66f-456b-aff7-b556a7eafe81"))
+	require.Error(t, client.ExpireSilence(context.Background(), "00000000-0000-0000-0000-000000000000"))
+}
+
+func TestListAlertGroups(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v2/alerts/groups", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "admin", r.URL.Query().Get("receiver"))
+		require.Equal(t, []string{"severity=none"}, r.URL.Query()["filter"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{
+			"labels": {"alertname": "Watchdog"},
+			"receiver": {"name": "admin"},
+			"alerts": ` + jsonAlerts + `
+		}, {
+			"labels": {"alertname": "Unprocessed"},
+			"receiver": {"name": "admin"},
+			"alerts": [{
+				"fingerprint": "0000000000000001",
+				"labels": {"alertname": "Unprocessed"},
+				"annotations": {},
+				"receivers": [{"name": "admin"}],
+				"startsAt": "2021-01-27T16:56:37.000Z",
+				"endsAt": "2021-02-22T00:52:37.000Z",
+				"updatedAt": "2021-02-22T00:48:37.000Z",
+				"status": {"inhibitedBy": [], "silencedBy": [], "state": "unprocessed"}
+			}]
+		}]`))
+	})
+
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	groups, err := client.ListAlertGroups(context.Background(), AlertFilter{
+		Receiver: "admin",
+		Matchers: []string{"severity=none"},
+		Active:   true,
+	})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, model.LabelSet{"alertname": "Watchdog"}, groups[0].Labels)
+	require.Equal(t, "admin", groups[0].Receiver)
+	require.Len(t, groups[0].Alerts, 1)
+	require.Equal(t, "7a90bbdd1d39f61b", groups[0].Alerts[0].Fingerprint)
+	require.Equal(t, []string{"healthcheck"}, groups[0].Alerts[0].Receivers)
+}
+
+func TestListSilencesFilter(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v2/silences", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, []string{"alertname=NodeDown"}, r.URL.Query()["filter"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{
+			"id": "1", "createdBy": "elliot", "comment": "", "updatedAt": "2021-01-11T16:10:11.000Z",
+			"startsAt": "2021-01-11T16:10:11.000Z", "endsAt": "2021-01-11T18:10:11.000Z",
+			"matchers": [{"name": "alertname", "value": "NodeDown", "isRegex": false}],
+			"status": {"state": "expired"}
+		}, {
+			"id": "2", "createdBy": "elliot", "comment": "", "updatedAt": "2021-01-11T16:10:11.000Z",
+			"startsAt": "2021-01-11T16:10:11.000Z", "endsAt": "2031-01-11T18:10:11.000Z",
+			"matchers": [{"name": "alertname", "value": "NodeDown", "isRegex": false}],
+			"status": {"state": "active"}
+		}, {
+			"id": "3", "createdBy": "darlene", "comment": "", "updatedAt": "2021-01-11T16:10:11.000Z",
+			"startsAt": "2030-01-11T16:10:11.000Z", "endsAt": "2031-01-11T18:10:11.000Z",
+			"matchers": [{"name": "alertname", "value": "NodeDown", "isRegex": false}],
+			"status": {"state": "pending"}
+		}]`))
+	})
+
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	ids := func(silences []*types.Silence) []string {
+		var ids []string
+		for _, s := range silences {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+
+	silences, err := client.ListSilences(context.Background(), SilenceFilter{
+		Matchers: []string{"alertname=NodeDown"},
+		States:   []types.SilenceState{types.SilenceStateActive, types.SilenceStatePending},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2", "3"}, ids(silences))
+
+	silences, err = client.ListSilences(context.Background(), SilenceFilter{
+		Matchers:  []string{"alertname=NodeDown"},
+		CreatedBy: "elliot",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, ids(silences))
+}
+
+func TestGetSilence(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v2/silence/34f5f82b-b66f-456b-aff7-b556a7eafe81", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "34f5f82b-b66f-456b-aff7-b556a7eafe81", "createdBy": "elliot", "comment": "maintenance",
+			"updatedAt": "2021-01-11T16:10:11.000Z", "startsAt": "2021-01-11T16:10:11.000Z", "endsAt": "2021-01-11T18:10:11.000Z",
+			"matchers": [{"name": "alertname", "value": "NodeDown", "isRegex": false}],
+			"status": {"state": "active"}
+		}`))
+	})
+	m.HandleFunc("/api/v2/silence/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	silence, err := client.GetSilence(context.Background(), "34f5f82b-b66f-456b-aff7-b556a7eafe81")
+	require.NoError(t, err)
+	require.Equal(t, &types.Silence{
+		ID:        "34f5f82b-b66f-456b-aff7-b556a7eafe81",
+		CreatedBy: "elliot",
+		Comment:   "maintenance",
+		StartsAt:  time.Date(2021, 01, 11, 16, 10, 11, 0, time.UTC),
+		EndsAt:    time.Date(2021, 01, 11, 18, 10, 11, 0, time.UTC),
+		UpdatedAt: time.Date(2021, 01, 11, 16, 10, 11, 0, time.UTC),
+		Matchers:  labels.Matchers{{Type: labels.MatchEqual, Name: "alertname", Value: "NodeDown"}},
+		Status:    types.SilenceStatus{State: types.SilenceStateActive},
+	}, silence)
+
+	silence, err = client.GetSilence(context.Background(), "00000000-0000-0000-0000-000000000000")
+	require.NoError(t, err)
+	require.Nil(t, silence)
+}
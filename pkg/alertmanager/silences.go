@@ -3,82 +3,216 @@ package alertmanager
 import (
 	"context"
 	"fmt"
+	"html"
 	"strings"
 	"time"
 
+	"github.com/go-openapi/strfmt"
 	"github.com/hako/durafmt"
 	"github.com/prometheus/alertmanager/api/v2/client/silence"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/pkg/labels"
 	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
 )
 
-func (c *Client) ListSilences(ctx context.Context) ([]*types.Silence, error) {
-	getSilences, err := c.alertmanager.Silence.GetSilences(silence.NewGetSilencesParams().WithContext(ctx))
+// SilenceFilter selects the silences to list.
+type SilenceFilter struct {
+	// Matchers the silences' matchers need to match, like alertname=NodeDown.
+	Matchers []string
+	// States of the silences, silences in any state if empty.
+	States []types.SilenceState
+	// CreatedBy is the creator of the silences, silences of all creators if empty.
+	CreatedBy string
+}
+
+// ListSilences lists the silences selected by the filter.
+func (c *Client) ListSilences(ctx context.Context, filter SilenceFilter) ([]*types.Silence, error) {
+	params := silence.NewGetSilencesParams().WithContext(ctx)
+	if len(filter.Matchers) > 0 {
+		params = params.WithFilter(filter.Matchers)
+	}
+
+	getSilences, err := c.alertmanager.Silence.GetSilences(params)
 	if err != nil {
 		return nil, err
 	}
 
 	silences := make([]*types.Silence, 0, len(getSilences.Payload))
 	for _, s := range getSilences.Payload {
-		var matchers = make([]*types.Matcher, 0, len(s.Matchers))
-		for _, m := range matchers {
-			matchers = append(matchers, &types.Matcher{
-				Name:    m.Name,
-				Value:   m.Value,
-				IsRegex: m.IsRegex,
-			})
+		if !filter.matches(s) {
+			continue
 		}
 
-		silences = append(silences, &types.Silence{
-			ID:        *s.ID,
-			StartsAt:  time.Time(*s.StartsAt),
-			EndsAt:    time.Time(*s.EndsAt),
-			UpdatedAt: time.Time(*s.UpdatedAt),
-			CreatedBy: *s.CreatedBy,
-			Comment:   *s.Comment,
-			Matchers:  matchers,
-			Status: types.SilenceStatus{
-				State: types.SilenceState(*s.Status.State),
-			},
-		})
+		sil, err := silenceFromAPI(s)
+		if err != nil {
+			return nil, err
+		}
+		silences = append(silences, sil)
 	}
 
 	return silences, nil
 }
 
-// SilenceMessage converts a silences to a message string.
-func SilenceMessage(s *types.Silence) string {
-	var alertname, emoji, matchers, duration string
+// GetSilence returns the silence with the ID or nil if there's no such silence.
+func (c *Client) GetSilence(ctx context.Context, id string) (*types.Silence, error) {
+	getSilence, err := c.alertmanager.Silence.GetSilence(silence.NewGetSilenceParams().WithContext(ctx).
+		WithSilenceID(strfmt.UUID(id)),
+	)
+	if err != nil {
+		if _, ok := err.(*silence.GetSilenceNotFound); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return silenceFromAPI(getSilence.Payload)
+}
+
+func silenceFromAPI(s *models.GettableSilence) (*types.Silence, error) {
+	matchers := make(labels.Matchers, 0, len(s.Matchers))
+	for _, m := range s.Matchers {
+		matcher, err := matcherFromAPI(m)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	return &types.Silence{
+		ID:        *s.ID,
+		StartsAt:  time.Time(*s.StartsAt),
+		EndsAt:    time.Time(*s.EndsAt),
+		UpdatedAt: time.Time(*s.UpdatedAt),
+		CreatedBy: *s.CreatedBy,
+		Comment:   *s.Comment,
+		Matchers:  matchers,
+		Status: types.SilenceStatus{
+			State: types.SilenceState(*s.Status.State),
+		},
+	}, nil
+}
+
+// matches returns whether the silence is in one of the filter's states and created by its creator.
+// The API only filters by matchers.
+func (f SilenceFilter) matches(s *models.GettableSilence) bool {
+	if f.CreatedBy != "" && (s.CreatedBy == nil || *s.CreatedBy != f.CreatedBy) {
+		return false
+	}
+	if len(f.States) == 0 {
+		return true
+	}
+	for _, state := range f.States {
+		if s.Status != nil && s.Status.State != nil && types.SilenceState(*s.Status.State) == state {
+			return true
+		}
+	}
+	return false
+}
+
+// matcherFromAPI converts a matcher of the API, which is an equality matcher unless isEqual is false.
+func matcherFromAPI(m *models.Matcher) (*labels.Matcher, error) {
+	isEqual := m.IsEqual == nil || *m.IsEqual
+	isRegex := m.IsRegex != nil && *m.IsRegex
 
+	t := labels.MatchEqual
+	switch {
+	case isRegex && isEqual:
+		t = labels.MatchRegexp
+	case isRegex:
+		t = labels.MatchNotRegexp
+	case !isEqual:
+		t = labels.MatchNotEqual
+	}
+
+	return labels.NewMatcher(t, *m.Name, *m.Value)
+}
+
+// CreateSilence creates the silence in the Alertmanager and returns its ID.
+func (c *Client) CreateSilence(ctx context.Context, s *types.Silence) (string, error) {
+	matchers := make(models.Matchers, 0, len(s.Matchers))
 	for _, m := range s.Matchers {
-		if m.Name == "alertname" {
+		name, value := m.Name, m.Value
+		isRegex := m.Type == labels.MatchRegexp || m.Type == labels.MatchNotRegexp
+		isEqual := m.Type == labels.MatchEqual || m.Type == labels.MatchRegexp
+		matchers = append(matchers, &models.Matcher{
+			Name:    &name,
+			Value:   &value,
+			IsRegex: &isRegex,
+			IsEqual: &isEqual,
+		})
+	}
+
+	startsAt := strfmt.DateTime(s.StartsAt)
+	endsAt := strfmt.DateTime(s.EndsAt)
+
+	postSilence, err := c.alertmanager.Silence.PostSilences(silence.NewPostSilencesParams().WithContext(ctx).
+		WithSilence(&models.PostableSilence{
+			ID: s.ID,
+			Silence: models.Silence{
+				Comment:   &s.Comment,
+				CreatedBy: &s.CreatedBy,
+				StartsAt:  &startsAt,
+				EndsAt:    &endsAt,
+				Matchers:  matchers,
+			},
+		}),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return postSilence.Payload.SilenceID, nil
+}
+
+// ExpireSilence expires the silence with the ID in the Alertmanager.
+func (c *Client) ExpireSilence(ctx context.Context, id string) error {
+	_, err := c.alertmanager.Silence.DeleteSilence(silence.NewDeleteSilenceParams().WithContext(ctx).
+		WithSilenceID(strfmt.UUID(id)),
+	)
+	return err
+}
+
+// SilenceMessage converts a silence to an HTML message string.
+func SilenceMessage(s *types.Silence) string {
+	var alertname string
+	matchers := make([]string, 0, len(s.Matchers))
+	for _, m := range s.Matchers {
+		if m.Name == model.AlertNameLabel && m.Type == labels.MatchEqual {
 			alertname = m.Value
-		} else {
-			matchers = matchers + fmt.Sprintf(` %s="%s"`, m.Name, m.Value)
+			continue
+		}
+		matchers = append(matchers, m.String())
+	}
+
+	var sb strings.Builder
+	if alertname != "" {
+		sb.WriteString("<b>" + html.EscapeString(alertname) + "</b>")
+		if !Resolved(s) {
+			sb.WriteString(" 🔕")
 		}
+		sb.WriteString("\n")
+	}
+	if len(matchers) > 0 {
+		fmt.Fprintf(&sb, "<code>%s</code>\n", html.EscapeString(strings.Join(matchers, " ")))
+	}
+	fmt.Fprintf(&sb, "<b>ID:</b> <code>%s</code>\n", html.EscapeString(s.ID))
+	if s.CreatedBy != "" {
+		fmt.Fprintf(&sb, "<b>Created by:</b> %s\n", html.EscapeString(s.CreatedBy))
+	}
+	if s.Comment != "" {
+		fmt.Fprintf(&sb, "<b>Comment:</b> %s\n", html.EscapeString(s.Comment))
 	}
 
-	resolved := Resolved(s)
-	if !resolved {
-		emoji = " 🔕"
-		duration = fmt.Sprintf(
-			"*Started*: %s ago\n*Ends:* %s\n",
-			durafmt.Parse(time.Since(s.StartsAt)),
-			durafmt.Parse(time.Since(s.EndsAt)),
-		)
+	if !Resolved(s) {
+		fmt.Fprintf(&sb, "<b>Started:</b> %s ago\n", durafmt.Parse(time.Since(s.StartsAt).Round(time.Second)))
+		fmt.Fprintf(&sb, "<b>Ends:</b> in %s\n", durafmt.Parse(time.Until(s.EndsAt).Round(time.Second)))
 	} else {
-		duration = fmt.Sprintf(
-			"*Ended*: %s ago\n*Duration*: %s",
-			durafmt.Parse(time.Since(s.EndsAt)),
-			durafmt.Parse(s.EndsAt.Sub(s.StartsAt)),
-		)
-	}
-
-	return fmt.Sprintf(
-		"%s%s\n```%s```\n%s\n",
-		alertname, emoji,
-		strings.TrimSpace(matchers),
-		duration,
-	)
+		fmt.Fprintf(&sb, "<b>Ended:</b> %s ago\n", durafmt.Parse(time.Since(s.EndsAt).Round(time.Second)))
+		fmt.Fprintf(&sb, "<b>Duration:</b> %s\n", durafmt.Parse(s.EndsAt.Sub(s.StartsAt).Round(time.Second)))
+	}
+
+	return sb.String()
 }
 
 // Resolved returns if a silence is resolved by EndsAt.
@@ -4,8 +4,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/alertmanager/pkg/labels"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestResolved(t *testing.T) {
@@ -18,3 +20,48 @@ func TestResolved(t *testing.T) {
 	s.EndsAt = time.Now().Add(-1 * time.Minute)
 	assert.True(t, Resolved(s))
 }
+
+func TestSilenceMessage(t *testing.T) {
+	matcher := func(t *testing.T, mt labels.MatchType, name, value string) *labels.Matcher {
+		m, err := labels.NewMatcher(mt, name, value)
+		require.NoError(t, err)
+		return m
+	}
+
+	s := &types.Silence{
+		ID: "34f5f82b-b66f-456b-aff7-b556a7eafe81",
+		Matchers: labels.Matchers{
+			matcher(t, labels.MatchEqual, "alertname", "NodeDown"),
+			matcher(t, labels.MatchNotEqual, "env", "dev"),
+			matcher(t, labels.MatchRegexp, "instance", "node-.*"),
+			matcher(t, labels.MatchNotRegexp, "job", "test|<staging>"),
+		},
+		StartsAt:  time.Now().Add(-time.Hour),
+		EndsAt:    time.Now().Add(2 * time.Hour),
+		CreatedBy: "elliot",
+		Comment:   "Replacing the disks & the fans",
+	}
+
+	assert.Equal(t, "<b>NodeDown</b> 🔕\n"+
+		`<code>env!=&#34;dev&#34; instance=~&#34;node-.*&#34; job!~&#34;test|&lt;staging&gt;&#34;</code>`+"\n"+
+		"<b>ID:</b> <code>34f5f82b-b66f-456b-aff7-b556a7eafe81</code>\n"+
+		"<b>Created by:</b> elliot\n"+
+		"<b>Comment:</b> Replacing the disks &amp; the fans\n"+
+		"<b>Started:</b> 1 hour ago\n"+
+		"<b>Ends:</b> in 2 hours\n",
+		SilenceMessage(s),
+	)
+
+	s.Matchers[0] = matcher(t, labels.MatchRegexp, "alertname", "Node.*")
+	s.StartsAt = time.Now().Add(-3 * time.Hour)
+	s.EndsAt = time.Now().Add(-time.Hour)
+	s.Comment = ""
+
+	assert.Equal(t, `<code>alertname=~&#34;Node.*&#34; env!=&#34;dev&#34; instance=~&#34;node-.*&#34; job!~&#34;test|&lt;staging&gt;&#34;</code>`+"\n"+
+		"<b>ID:</b> <code>34f5f82b-b66f-456b-aff7-b556a7eafe81</code>\n"+
+		"<b>Created by:</b> elliot\n"+
+		"<b>Ended:</b> 1 hour ago\n"+
+		"<b>Duration:</b> 2 hours\n",
+		SilenceMessage(s),
+	)
+}
@@ -12,50 +12,164 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// webhookBroadcast is the path element used instead of a chat ID
+// to deliver a webhook to the subscribed chats bound to its receiver.
+const webhookBroadcast = "broadcast"
+
 type TelegramWebhook struct {
-	ChatID  int64
-	Message webhook.Message
+	ChatID int64
+	// Broadcast webhooks are sent to the subscribed chats bound to the webhook's receiver with /receiver instead of ChatID.
+	Broadcast bool
+	// Topic webhooks are sent to all chats subscribed to the topic instead of ChatID.
+	Topic string
+	// OnCall webhooks are sent to the private chat of the rotation's current on-call user instead of ChatID.
+	OnCall string
+	// Watchdog webhooks tell the bot the alerting pipeline works and aren't sent to any chat.
+	Watchdog bool
+	Message  webhook.Message
 }
 
 // HandleTelegramWebhook returns a HandlerFunc that forwards webhooks to all bots via a channel.
 func HandleTelegramWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<- TelegramWebhook) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			w.WriteHeader(http.StatusMethodNotAllowed)
+		message, ok := readWebhook(logger, w, r)
+		if !ok {
 			return
 		}
 
-		if r.Body == nil {
-			w.WriteHeader(http.StatusBadRequest)
+		var (
+			chatID    int64
+			broadcast bool
+		)
+		if target := strings.TrimPrefix(r.URL.Path, "/webhooks/telegram/"); target == webhookBroadcast {
+			broadcast = true
+		} else {
+			id, err := strconv.ParseInt(target, 10, 64)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"unable to parse chat ID to int64"}`))
+				return
+			}
+			chatID = id
+		}
+
+		level.Debug(logger).Log(
+			"msg", "received webhook",
+			"alerts", len(message.Alerts),
+			"chat_id", chatID,
+			"broadcast", broadcast,
+		)
+
+		webhooks <- TelegramWebhook{ChatID: chatID, Broadcast: broadcast, Message: message}
+		counter.Inc()
+	}
+}
+
+// HandleTopicWebhook returns a HandlerFunc that forwards webhooks for a topic to all bots via a channel.
+func HandleTopicWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<- TelegramWebhook) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		message, ok := readWebhook(logger, w, r)
+		if !ok {
 			return
 		}
-		defer r.Body.Close()
 
-		chatID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/webhooks/telegram/"), 10, 64)
-		if err != nil {
+		topic, ok := pathName(r, "/webhooks/topic/")
+		if !ok {
 			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(`{"error":"unable to parse chat ID to int64"}`))
+			_, _ = w.Write([]byte(`{"error":"invalid topic name"}`))
 			return
 		}
 
-		var message webhook.Message
+		level.Debug(logger).Log(
+			"msg", "received webhook",
+			"alerts", len(message.Alerts),
+			"topic", topic,
+		)
+
+		webhooks <- TelegramWebhook{Topic: topic, Message: message}
+		counter.Inc()
+	}
+}
+
+// HandleOnCallWebhook returns a HandlerFunc that forwards webhooks for an on-call rotation to all bots via a channel.
+func HandleOnCallWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<- TelegramWebhook) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		message, ok := readWebhook(logger, w, r)
+		if !ok {
+			return
+		}
 
-		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
-			level.Warn(logger).Log(
-				"msg", "failed to decode webhook message",
-				"err", err,
-			)
+		rotation, ok := pathName(r, "/webhooks/oncall/")
+		if !ok {
 			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid rotation name"}`))
 			return
 		}
 
 		level.Debug(logger).Log(
 			"msg", "received webhook",
 			"alerts", len(message.Alerts),
-			"chat_id", chatID,
+			"rotation", rotation,
+		)
+
+		webhooks <- TelegramWebhook{OnCall: rotation, Message: message}
+		counter.Inc()
+	}
+}
+
+// HandleWatchdogWebhook returns a HandlerFunc that forwards webhooks with the watchdog alert to all bots via a channel.
+func HandleWatchdogWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<- TelegramWebhook) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		message, ok := readWebhook(logger, w, r)
+		if !ok {
+			return
+		}
+
+		level.Debug(logger).Log(
+			"msg", "received webhook",
+			"alerts", len(message.Alerts),
+			"watchdog", true,
 		)
 
-		webhooks <- TelegramWebhook{ChatID: chatID, Message: message}
+		webhooks <- TelegramWebhook{Watchdog: true, Message: message}
 		counter.Inc()
 	}
 }
+
+// pathName returns the name following the prefix in the request's path.
+// It returns false if the name is empty or has more path elements.
+func pathName(r *http.Request, prefix string) (string, bool) {
+	name := strings.TrimPrefix(r.URL.Path, prefix)
+	if name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// readWebhook decodes the webhook message from a request's body.
+// If the request is invalid the response is written and false returned.
+func readWebhook(logger log.Logger, w http.ResponseWriter, r *http.Request) (webhook.Message, bool) {
+	var message webhook.Message
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return message, false
+	}
+
+	if r.Body == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return message, false
+	}
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		level.Warn(logger).Log(
+			"msg", "failed to decode webhook message",
+			"err", err,
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return message, false
+	}
+
+	return message, true
+}
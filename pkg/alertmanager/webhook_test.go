@@ -119,6 +119,29 @@ func TestHandleWebhook(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ValidWebhookBroadcast",
+			req: func() *http.Request {
+				body := bytes.NewBufferString(validWebhook)
+				req, _ := http.NewRequest(http.MethodPost, "/webhooks/telegram/broadcast", body)
+				return req
+			},
+			checks: []checkFunc{
+				checkStatusCode(http.StatusOK),
+				func(resp *http.Response) error {
+					var expected webhook.Message
+					if err := json.Unmarshal([]byte(validWebhook), &expected); err != nil {
+						return err
+					}
+
+					webhook := <-webhooks
+					if !assert.Equal(t, TelegramWebhook{Broadcast: true, Message: expected}, webhook) {
+						return errors.New("")
+					}
+					return nil
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -134,3 +157,70 @@ func TestHandleWebhook(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleTopicWebhook(t *testing.T) {
+	logger := log.NewNopLogger()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{})
+	webhooks := make(chan TelegramWebhook, 1)
+
+	h := HandleTopicWebhook(logger, counter, webhooks)
+
+	{
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/topic/", bytes.NewBufferString(validWebhook))
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	}
+	{
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/topic/db", bytes.NewBufferString(validWebhook))
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+
+		var expected webhook.Message
+		assert.NoError(t, json.Unmarshal([]byte(validWebhook), &expected))
+		assert.Equal(t, TelegramWebhook{Topic: "db", Message: expected}, <-webhooks)
+	}
+}
+
+func TestHandleOnCallWebhook(t *testing.T) {
+	logger := log.NewNopLogger()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{})
+	webhooks := make(chan TelegramWebhook, 1)
+
+	h := HandleOnCallWebhook(logger, counter, webhooks)
+
+	{
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/oncall/ops/primary", bytes.NewBufferString(validWebhook))
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+	}
+	{
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/oncall/ops", bytes.NewBufferString(validWebhook))
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+
+		var expected webhook.Message
+		assert.NoError(t, json.Unmarshal([]byte(validWebhook), &expected))
+		assert.Equal(t, TelegramWebhook{OnCall: "ops", Message: expected}, <-webhooks)
+	}
+}
+
+func TestHandleWatchdogWebhook(t *testing.T) {
+	logger := log.NewNopLogger()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{})
+	webhooks := make(chan TelegramWebhook, 1)
+
+	h := HandleWatchdogWebhook(logger, counter, webhooks)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/webhooks/watchdog", bytes.NewBufferString(validWebhook))
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+
+	var expected webhook.Message
+	assert.NoError(t, json.Unmarshal([]byte(validWebhook), &expected))
+	assert.Equal(t, TelegramWebhook{Watchdog: true, Message: expected}, <-webhooks)
+}
@@ -0,0 +1,528 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseAckNotFound  = "I can't find a firing alert for %s in this chat."
+	responseAckAmbiguous = "%s matches more than one alert group, please be more specific."
+	responseAckNone      = "There are no unacknowledged alerts in this chat. 🎉"
+	responseAckResolved  = "This alert isn't firing anymore."
+	responseAcked        = "Acknowledged by %s."
+	responseAckedAlready = "Already acknowledged by %s."
+	responseAckReminder  = "⏰ This alert is still firing and nobody acknowledged it yet.\n" + CommandAck
+)
+
+// ackButton is the inline button to acknowledge the alert group of a notification.
+var ackButton = telebot.InlineButton{Unique: "ack", Text: "✋ Acknowledge"}
+
+// Notification is a message sent to a chat for a firing alert group.
+type Notification struct {
+	GroupKey     string            `json:"groupKey"`
+	ChatID       int64             `json:"chatId"`
+	MessageID    int               `json:"messageId"`
+	Text         string            `json:"text"`
+	Fingerprints []string          `json:"fingerprints"`
+	Labels       map[string]string `json:"labels"`
+	SentAt       time.Time         `json:"sentAt"`
+	AckedBy      string            `json:"ackedBy,omitempty"`
+	AckedAt      time.Time         `json:"ackedAt,omitempty"`
+	RemindedAt   time.Time         `json:"remindedAt,omitempty"`
+	// UpdatedAt is when the last webhook of the still firing alert group arrived.
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+
+	// Escalations is the number of escalation steps already taken.
+	Escalations int `json:"escalations,omitempty"`
+	// EscalationFailures is the number of failed attempts to take the next escalation step.
+	EscalationFailures int `json:"escalationFailures,omitempty"`
+	// EscalatedTo are the chats the notification has been escalated to.
+	EscalatedTo []int64 `json:"escalatedTo,omitempty"`
+	// Origin is the chat an escalated notification has been escalated from.
+	Origin int64 `json:"origin,omitempty"`
+}
+
+// ID of the notification's alert group, short enough for callback data.
+func (n *Notification) ID() string {
+	return groupID(n.GroupKey)
+}
+
+// Acked returns whether somebody acknowledged the notification's alert group.
+func (n *Notification) Acked() bool {
+	return n.AckedBy != ""
+}
+
+// lastSeen returns when the bot last heard of the notification's alert group firing.
+func (n *Notification) lastSeen() time.Time {
+	if n.UpdatedAt.After(n.SentAt) {
+		return n.UpdatedAt
+	}
+	return n.SentAt
+}
+
+// groupID hashes an Alertmanager group key to a short ID.
+func groupID(groupKey string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(groupKey))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// NotificationStore writes notifications of firing alert groups to a libkv store backend.
+type NotificationStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewNotificationStore stores notifications in the provided kv backend.
+func NewNotificationStore(kv store.Store, storeKeyPrefix string) (*NotificationStore, error) {
+	return &NotificationStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all notifications saved in the kv backend.
+func (s *NotificationStore) List() ([]*Notification, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	notifications := make([]*Notification, 0, len(kvPairs))
+	for _, kv := range kvPairs {
+		var n *Notification
+		if err := json.Unmarshal(kv.Value, &n); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, nil
+}
+
+// Get the notification of an alert group in a chat, nil if there is none.
+func (s *NotificationStore) Get(chatID int64, id string) (*Notification, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d/%s", s.storeKeyPrefix, chatID, id))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var n *Notification
+	err = json.Unmarshal(kv.Value, &n)
+	return n, err
+}
+
+// Put a notification into the kv backend, replacing the alert group's previous one.
+func (s *NotificationStore) Put(n *Notification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d/%s", s.storeKeyPrefix, n.ChatID, n.ID()), b, nil)
+}
+
+// Remove the notification of an alert group in a chat.
+func (s *NotificationStore) Remove(chatID int64, id string) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d/%s", s.storeKeyPrefix, chatID, id))
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// displayName returns how a user is mentioned in messages.
+func displayName(u *telebot.User) string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	if u.FirstName != "" {
+		return u.FirstName
+	}
+	return strconv.Itoa(u.ID)
+}
+
+// ackedText is the notification's text showing who acknowledged it.
+func (n *Notification) ackedText() string {
+	return strings.TrimSpace(n.Text) + "\n\n✋ <b>Acked by</b> " + html.EscapeString(n.AckedBy)
+}
+
+// notificationOptions returns the options for sending a webhook's message to chats.
+// Firing alert groups get a button to acknowledge them, if notifications are tracked.
+func (b *Bot) notificationOptions(m webhook.Message) *telebot.SendOptions {
+	opts := &telebot.SendOptions{ParseMode: telebot.ModeHTML}
+	if b.notifications != nil && m.Status == string(model.AlertFiring) {
+		button := ackButton
+		button.Data = groupID(m.GroupKey)
+		opts.ReplyMarkup = &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{button}}}
+	}
+	return opts
+}
+
+// trackNotification remembers the message sent for a firing alert group,
+// to acknowledge the alert group later on. Resolved alert groups are forgotten.
+func (b *Bot) trackNotification(chat *telebot.Chat, sent *telebot.Message, m webhook.Message, text string) {
+	if b.notifications == nil {
+		return
+	}
+
+	b.notificationsMu.Lock()
+	defer b.notificationsMu.Unlock()
+
+	id := groupID(m.GroupKey)
+
+	previous, err := b.notifications.Get(chat.ID, id)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get notification", "chat_id", chat.ID, "err", err)
+	}
+
+	if m.Status != string(model.AlertFiring) {
+		chatIDs := []int64{chat.ID}
+		if previous != nil {
+			chatIDs = append(chatIDs, previous.EscalatedTo...)
+		}
+		for _, chatID := range chatIDs {
+			if err := b.notifications.Remove(chatID, id); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to remove notification", "chat_id", chatID, "err", err)
+			}
+		}
+		return
+	}
+
+	n := &Notification{
+		GroupKey:  m.GroupKey,
+		ChatID:    chat.ID,
+		Text:      text,
+		Labels:    m.CommonLabels,
+		SentAt:    time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if sent != nil {
+		n.MessageID = sent.ID
+	}
+	for _, a := range m.Alerts {
+		if a.Status == string(model.AlertFiring) {
+			n.Fingerprints = append(n.Fingerprints, a.Fingerprint)
+		}
+	}
+
+	// The alert group is still firing since the previous notification,
+	// so reminders and escalations continue from where they are.
+	if previous != nil {
+		n.SentAt = previous.SentAt
+		n.RemindedAt = previous.RemindedAt
+		n.Escalations = previous.Escalations
+		n.EscalatedTo = previous.EscalatedTo
+	}
+	if previous != nil && previous.Acked() {
+		n.AckedBy = previous.AckedBy
+		n.AckedAt = previous.AckedAt
+		b.editNotification(n)
+	}
+
+	if err := b.notifications.Put(n); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to store notification", "chat_id", chat.ID, "err", err)
+	}
+}
+
+// editNotification updates the notification's message to show who acknowledged it.
+func (b *Bot) editNotification(n *Notification) {
+	msg := &telebot.StoredMessage{MessageID: strconv.Itoa(n.MessageID), ChatID: n.ChatID}
+	if _, err := b.telegram.Edit(msg, n.ackedText(), &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to edit notification", "chat_id", n.ChatID, "err", err)
+	}
+}
+
+// ack records the user acknowledging the notification's alert group.
+// It returns the response for the user.
+func (b *Bot) ack(chatID int64, id string, user *telebot.User) (string, error) {
+	b.notificationsMu.Lock()
+	defer b.notificationsMu.Unlock()
+
+	n, err := b.notifications.Get(chatID, id)
+	if err != nil {
+		return "", err
+	}
+	if n == nil {
+		return responseAckResolved, nil
+	}
+	if n.Acked() {
+		return fmt.Sprintf(responseAckedAlready, n.AckedBy), nil
+	}
+
+	n.AckedBy = displayName(user)
+	n.AckedAt = time.Now()
+	if err := b.notifications.Put(n); err != nil {
+		return "", err
+	}
+	b.editNotification(n)
+	b.ackEscalations(n)
+
+	level.Info(b.logger).Log(
+		"msg", "alert group acknowledged",
+		"group_key", n.GroupKey,
+		"chat_id", chatID,
+		"user_id", user.ID,
+	)
+
+	return fmt.Sprintf(responseAcked, n.AckedBy), nil
+}
+
+// ackEscalations acknowledges the notifications of the same alert group
+// in the chats it was escalated from or to.
+func (b *Bot) ackEscalations(acked *Notification) {
+	origin := acked
+	if acked.Origin != 0 {
+		var err error
+		origin, err = b.notifications.Get(acked.Origin, acked.ID())
+		if err != nil || origin == nil {
+			return
+		}
+	}
+
+	for _, chatID := range append([]int64{origin.ChatID}, origin.EscalatedTo...) {
+		if chatID == acked.ChatID {
+			continue
+		}
+		n, err := b.notifications.Get(chatID, acked.ID())
+		if err != nil || n == nil || n.Acked() {
+			continue
+		}
+		n.AckedBy = acked.AckedBy
+		n.AckedAt = acked.AckedAt
+		if err := b.notifications.Put(n); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to store notification", "chat_id", chatID, "err", err)
+			continue
+		}
+		b.editNotification(n)
+	}
+}
+
+// chatNotifications returns the chat's notifications sorted by the time they were sent.
+func (b *Bot) chatNotifications(chatID int64) ([]*Notification, error) {
+	all, err := b.notifications.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []*Notification
+	for _, n := range all {
+		if n.ChatID == chatID {
+			notifications = append(notifications, n)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].SentAt.Before(notifications[j].SentAt)
+	})
+
+	return notifications, nil
+}
+
+func (b *Bot) handleAck(message *telebot.Message) error {
+	if b.notifications == nil {
+		_, err := b.telegram.Send(message.Chat, "I don't keep track of alerts to acknowledge.")
+		return err
+	}
+
+	notifications, err := b.chatNotifications(message.Chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list notifications", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the alerts to acknowledge.")
+		return err
+	}
+
+	arg := strings.TrimSpace(message.Payload)
+
+	var matches []*Notification
+	for _, n := range notifications {
+		switch {
+		case message.ReplyTo != nil && arg == "":
+			if n.MessageID == message.ReplyTo.ID {
+				matches = append(matches, n)
+			}
+		case arg == "":
+			if !n.Acked() {
+				matches = append(matches, n)
+			}
+		default:
+			if strings.HasPrefix(n.ID(), arg) {
+				matches = append(matches, n)
+				continue
+			}
+			for _, fp := range n.Fingerprints {
+				if strings.HasPrefix(fp, arg) {
+					matches = append(matches, n)
+					break
+				}
+			}
+		}
+	}
+
+	if arg == "" && message.ReplyTo == nil {
+		return b.sendUnacked(message.Chat, matches)
+	}
+	if len(matches) == 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseAckNotFound, arg))
+		return err
+	}
+	if len(matches) > 1 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseAckAmbiguous, arg))
+		return err
+	}
+
+	response, err := b.ack(message.Chat.ID, matches[0].ID(), message.Sender)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to acknowledge alert group", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't acknowledge the alert.")
+		return err
+	}
+
+	_, err = b.telegram.Send(message.Chat, response)
+	return err
+}
+
+// sendUnacked sends the unacknowledged notifications with their alerts' fingerprints to the chat.
+func (b *Bot) sendUnacked(chat *telebot.Chat, notifications []*Notification) error {
+	if len(notifications) == 0 {
+		_, err := b.telegram.Send(chat, responseAckNone)
+		return err
+	}
+
+	out := "Unacknowledged alerts:\n"
+	for _, n := range notifications {
+		out = out + fmt.Sprintf("%s %s\n", strings.Join(n.Fingerprints, ", "), n.Labels["alertname"])
+	}
+	out = out + "\nAcknowledge with " + CommandAck + " <fingerprint>."
+
+	_, err := b.telegram.Send(chat, out)
+	return err
+}
+
+func (b *Bot) handleAckButton(c *telebot.Callback) error {
+	response, err := b.ack(c.Message.Chat.ID, c.Data, c.Sender)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to acknowledge alert group", "err", err)
+		response = "I can't acknowledge the alert."
+	}
+	return b.telegram.Respond(c, &telebot.CallbackResponse{Text: response})
+}
+
+// runScheduler periodically reminds chats of alert groups still firing
+// without being acknowledged, escalates them to other chats and watches silences.
+func (b *Bot) runScheduler(ctx context.Context) error {
+	ticker := time.NewTicker(b.schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if b.notifications != nil && b.ackReminder > 0 {
+				if err := b.sendAckReminders(); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to send acknowledgement reminders", "err", err)
+				}
+			}
+			if b.notifications != nil && b.notificationMaxAge > 0 {
+				if err := b.pruneNotifications(); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to prune notifications", "err", err)
+				}
+			}
+			if b.notifications != nil && len(b.escalationPolicies) > 0 {
+				if err := b.escalate(); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to escalate alerts", "err", err)
+				}
+			}
+			if b.silences != nil && b.silenceReminder > 0 {
+				if err := b.watchSilences(); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to watch silences", "err", err)
+				}
+			}
+			if b.maintenance != nil {
+				if err := b.silenceMaintenanceWindows(); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to silence maintenance windows", "err", err)
+				}
+			}
+			if b.flapThreshold > 0 {
+				b.settleFlapping()
+			}
+			if b.watchdogTimeout > 0 {
+				b.checkWatchdog()
+			}
+		}
+	}
+}
+
+// pruneNotifications forgets the notifications of alert groups the bot didn't hear of for the max age.
+// Without resolved webhooks, when send_resolved is false, they would be reminded of and escalated forever.
+func (b *Bot) pruneNotifications() error {
+	b.notificationsMu.Lock()
+	defer b.notificationsMu.Unlock()
+
+	notifications, err := b.notifications.List()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		if time.Since(n.lastSeen()) < b.notificationMaxAge {
+			continue
+		}
+		for _, chatID := range append([]int64{n.ChatID}, n.EscalatedTo...) {
+			if err := b.notifications.Remove(chatID, n.ID()); err != nil {
+				return err
+			}
+		}
+		level.Info(b.logger).Log("msg", "notification expired", "chat_id", n.ChatID, "group_key", n.GroupKey)
+	}
+
+	return nil
+}
+
+func (b *Bot) sendAckReminders() error {
+	b.notificationsMu.Lock()
+	defer b.notificationsMu.Unlock()
+
+	notifications, err := b.notifications.List()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		if n.Acked() || n.Origin != 0 || !n.RemindedAt.IsZero() || time.Since(n.SentAt) < b.ackReminder {
+			continue
+		}
+
+		_, err := b.telegram.Send(&telebot.Chat{ID: n.ChatID}, responseAckReminder, &telebot.SendOptions{
+			ReplyTo: &telebot.Message{ID: n.MessageID},
+		})
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send acknowledgement reminder", "chat_id", n.ChatID, "err", err)
+			continue
+		}
+
+		n.RemindedAt = time.Now()
+		if err := b.notifications.Put(n); err != nil {
+			return err
+		}
+		b.auditAction(n.ChatID, "ack-reminder", n.GroupKey)
+	}
+
+	return nil
+}
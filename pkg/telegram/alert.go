@@ -0,0 +1,210 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseAlertUsage     = "Usage: " + CommandAlert + " <fingerprint>"
+	responseAlertNotFound  = "There is no alert with a fingerprint starting with %s."
+	responseAlertAmbiguous = "%s matches more than one alert:\n%s"
+	responseAlertSilenced  = "🔕 %s silenced %s for %s."
+)
+
+// silenceButton is the inline button to silence an alert by its fingerprint for a while.
+var silenceButton = telebot.InlineButton{Unique: "silence"}
+
+// silenceDurations are offered as buttons to silence an alert.
+var silenceDurations = []time.Duration{time.Hour, 4 * time.Hour, 24 * time.Hour}
+
+// findAlerts returns all alerts whose fingerprint starts with prefix.
+func (b *Bot) findAlerts(ctx context.Context, prefix string) ([]*alertmanager.Alert, error) {
+	alerts, err := b.alertmanager.ListAlerts(ctx, alertmanager.AlertFilter{
+		Active:      true,
+		Silenced:    true,
+		Inhibited:   true,
+		Unprocessed: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*alertmanager.Alert
+	for _, a := range alerts {
+		if a.Fingerprint == prefix {
+			return []*alertmanager.Alert{a}, nil
+		}
+		if strings.HasPrefix(a.Fingerprint, prefix) {
+			matches = append(matches, a)
+		}
+	}
+	return matches, nil
+}
+
+func (b *Bot) handleAlert(message *telebot.Message) error {
+	prefix := strings.TrimSpace(message.Payload)
+	if prefix == "" || strings.Contains(prefix, " ") {
+		_, err := b.telegram.Send(message.Chat, responseAlertUsage)
+		return err
+	}
+
+	alerts, err := b.findAlerts(context.TODO(), prefix)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alerts... %v", err))
+		return err
+	}
+
+	switch len(alerts) {
+	case 0:
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseAlertNotFound, prefix))
+		return err
+	case 1:
+	default:
+		list := ""
+		for _, a := range alerts {
+			list = list + fmt.Sprintf("%s %s\n", a.Fingerprint, a.Labels[model.AlertNameLabel])
+		}
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseAlertAmbiguous, prefix, list))
+		return err
+	}
+
+	a := alerts[0]
+
+	buttons := make([]telebot.InlineButton, 0, len(silenceDurations))
+	for _, d := range silenceDurations {
+		button := silenceButton
+		button.Text = "🔕 " + model.Duration(d).String()
+		button.Data = a.Fingerprint + " " + model.Duration(d).String()
+		buttons = append(buttons, button)
+	}
+
+	_, err = b.telegram.Send(message.Chat, alertDetails(a), &telebot.SendOptions{
+		ParseMode:             telebot.ModeHTML,
+		DisableWebPagePreview: true,
+		ReplyMarkup:           &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{buttons}},
+	})
+	return err
+}
+
+// alertDetails renders everything the Alertmanager knows about an alert as HTML.
+func alertDetails(a *alertmanager.Alert) string {
+	emoji := "🔥"
+	if a.Status.State == types.AlertStateSuppressed {
+		emoji = "🔕"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s <b>%s</b> %s\n", emoji, html.EscapeString(string(a.Labels[model.AlertNameLabel])), emoji)
+	fmt.Fprintf(&sb, "<b>Fingerprint:</b> <code>%s</code>\n", html.EscapeString(a.Fingerprint))
+	fmt.Fprintf(&sb, "<b>State:</b> %s\n", html.EscapeString(string(a.Status.State)))
+
+	sb.WriteString("<b>Labels:</b>\n")
+	writeLabelSet(&sb, a.Labels)
+	if len(a.Annotations) > 0 {
+		sb.WriteString("<b>Annotations:</b>\n")
+		writeLabelSet(&sb, a.Annotations)
+	}
+
+	fmt.Fprintf(&sb, "<b>Started:</b> %s ago\n", durafmt.Parse(time.Since(a.StartsAt).Round(time.Second)))
+	if len(a.Status.SilencedBy) > 0 {
+		fmt.Fprintf(&sb, "<b>Silenced by:</b> %s\n", html.EscapeString(strings.Join(a.Status.SilencedBy, ", ")))
+	}
+	if len(a.Status.InhibitedBy) > 0 {
+		fmt.Fprintf(&sb, "<b>Inhibited by:</b> %s\n", html.EscapeString(strings.Join(a.Status.InhibitedBy, ", ")))
+	}
+	if len(a.Receivers) > 0 {
+		fmt.Fprintf(&sb, "<b>Receivers:</b> %s\n", html.EscapeString(strings.Join(a.Receivers, ", ")))
+	}
+	if a.GeneratorURL != "" {
+		fmt.Fprintf(&sb, "<a href=\"%s\">Source</a>\n", html.EscapeString(a.GeneratorURL))
+	}
+
+	return sb.String()
+}
+
+// writeLabelSet writes the labels sorted by name, one per line.
+func writeLabelSet(sb *strings.Builder, ls model.LabelSet) {
+	names := make([]string, 0, len(ls))
+	for name := range ls {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(sb, "    %s: %s\n", html.EscapeString(name), html.EscapeString(string(ls[model.LabelName(name)])))
+	}
+}
+
+func (b *Bot) handleSilenceButton(c *telebot.Callback) error {
+	args := strings.Fields(c.Data)
+	if len(args) != 2 {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I don't understand this button."})
+	}
+	d, err := model.ParseDuration(args[1])
+	if err != nil || d <= 0 {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I don't understand this button."})
+	}
+
+	alerts, err := b.findAlerts(context.TODO(), args[0])
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't find the alert."})
+	}
+	if len(alerts) != 1 {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "The alert isn't there anymore."})
+	}
+	a := alerts[0]
+
+	matchers := make(labels.Matchers, 0, len(a.Labels))
+	for name, value := range a.Labels {
+		m, err := labels.NewMatcher(labels.MatchEqual, string(name), string(value))
+		if err != nil {
+			return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't silence the alert."})
+		}
+		matchers = append(matchers, m)
+	}
+	sort.Sort(matchers)
+
+	now := time.Now()
+	by := displayName(c.Sender)
+	id, err := b.createSilence(context.TODO(), &types.Silence{
+		Matchers:  matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(time.Duration(d)),
+		CreatedBy: by,
+		Comment:   "Silenced with Telegram",
+	}, c.Message.Chat, c.Sender)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to create silence", "err", err)
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't silence the alert."})
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "alert silenced",
+		"fingerprint", a.Fingerprint,
+		"silence_id", id,
+		"duration", d,
+		"user_id", c.Sender.ID,
+	)
+
+	response := fmt.Sprintf(responseAlertSilenced, by, a.Labels[model.AlertNameLabel], d)
+	if err := b.telegram.Respond(c, &telebot.CallbackResponse{Text: response}); err != nil {
+		return err
+	}
+	_, err = b.telegram.Send(c.Message.Chat, response)
+	return err
+}
@@ -0,0 +1,271 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseAlertsUsage = "Usage: " + CommandAlerts + " [all] [matchers...] [silenced] [inhibited] [unprocessed] [active] [sort:start|sort:severity]\n" +
+		"For example: " + CommandAlerts + " severity=critical job=~api.*"
+	responseAlertsOutdated = "This list is outdated, please ask for " + CommandAlerts + " again."
+
+	alertsPageSize  = 10
+	alertsPageBytes = 3800
+	// pageQueriesMax is the number of lists whose pagination buttons keep working.
+	pageQueriesMax = 100
+)
+
+// alertsPageButton is the inline button to show another page of alerts.
+var alertsPageButton = telebot.InlineButton{Unique: "alerts"}
+
+// severityOrder sorts alerts by their severity label, unknown severities last.
+var severityOrder = map[string]int{
+	"critical": 0,
+	"error":    1,
+	"warning":  2,
+	"info":     3,
+	"none":     4,
+}
+
+// alertQuery is what the user asked for with /alerts.
+type alertQuery struct {
+	filter   alertmanager.AlertFilter
+	sortBy   string
+	receiver string
+	chat     int64
+	// all lists the alerts of all receivers instead of only the chat's.
+	all bool
+}
+
+// parseAlertQuery parses the arguments of /alerts.
+// Without any state flags, all but silenced alerts are listed.
+func parseAlertQuery(payload string) (alertQuery, error) {
+	q := alertQuery{sortBy: "start"}
+
+	var silenced, states bool
+	for _, arg := range strings.Fields(payload) {
+		switch arg {
+		case "all":
+			q.all = true
+		case "silenced":
+			silenced = true
+		case "inhibited":
+			states = true
+			q.filter.Inhibited = true
+		case "unprocessed":
+			states = true
+			q.filter.Unprocessed = true
+		case "active":
+			states = true
+			q.filter.Active = true
+		case "sort:start":
+			q.sortBy = "start"
+		case "sort:severity":
+			q.sortBy = "severity"
+		default:
+			if _, err := labels.ParseMatcher(arg); err != nil {
+				return q, err
+			}
+			q.filter.Matchers = append(q.filter.Matchers, arg)
+		}
+	}
+
+	if !states {
+		q.filter.Active = true
+		q.filter.Inhibited = true
+		q.filter.Unprocessed = true
+	}
+	q.filter.Silenced = silenced
+
+	return q, nil
+}
+
+// sortAlerts sorts the alerts by start time, newest first, or by severity.
+func sortAlerts(alerts []*alertmanager.Alert, by string) {
+	severity := func(a *alertmanager.Alert) int {
+		if o, ok := severityOrder[string(a.Labels["severity"])]; ok {
+			return o
+		}
+		return len(severityOrder)
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if by == "severity" {
+			si, sj := severity(alerts[i]), severity(alerts[j])
+			if si != sj {
+				return si < sj
+			}
+		}
+		return alerts[i].StartsAt.After(alerts[j].StartsAt)
+	})
+}
+
+// pageQueries remembers the latest lists of alerts and silences and the silences waiting for confirmation,
+// so that their buttons keep working.
+type pageQueries struct {
+	mu      sync.Mutex
+	next    int
+	tokens  []string
+	queries map[string]interface{}
+}
+
+func (q *pageQueries) add(query interface{}) string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.queries == nil {
+		q.queries = map[string]interface{}{}
+	}
+
+	q.next++
+	token := strconv.Itoa(q.next)
+	q.tokens = append(q.tokens, token)
+	q.queries[token] = query
+
+	if len(q.tokens) > pageQueriesMax {
+		delete(q.queries, q.tokens[0])
+		q.tokens = q.tokens[1:]
+	}
+
+	return token
+}
+
+func (q *pageQueries) get(token string) (interface{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	query, ok := q.queries[token]
+	return query, ok
+}
+
+func (q *pageQueries) remove(token string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.queries, token)
+}
+
+// alertPages splits the alerts into pages fitting into a single message.
+func (b *Bot) alertPages(alerts []*alertmanager.Alert) ([][]*alertmanager.Alert, error) {
+	var (
+		pages [][]*alertmanager.Alert
+		page  []*alertmanager.Alert
+		size  int
+	)
+	for _, a := range alerts {
+		out, err := b.tmplAlerts(&a.Alert)
+		if err != nil {
+			return nil, err
+		}
+		n := len(strings.TrimSpace(out)) + 2
+
+		if len(page) > 0 && (len(page) == alertsPageSize || size+n > alertsPageBytes) {
+			pages = append(pages, page)
+			page, size = nil, 0
+		}
+		page = append(page, a)
+		size += n
+	}
+	if len(page) > 0 {
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// renderAlerts lists the alerts for the query and renders the requested page with its buttons.
+func (b *Bot) renderAlerts(token string, query alertQuery, page int) (string, *telebot.SendOptions, error) {
+	filter := query.filter
+	filter.Receiver = query.receiver
+
+	alerts, err := b.alertmanager.ListAlerts(context.TODO(), filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := &telebot.SendOptions{ParseMode: telebot.ModeHTML}
+	if len(alerts) == 0 {
+		return "No alerts right now! 🎉", opts, nil
+	}
+
+	sortAlerts(alerts, query.sortBy)
+
+	pages, err := b.alertPages(alerts)
+	if err != nil {
+		return "", nil, err
+	}
+	if page < 0 {
+		page = 0
+	}
+	if page >= len(pages) {
+		page = len(pages) - 1
+	}
+
+	out, err := b.tmplAlerts(typesAlerts(pages[page])...)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pages) == 1 {
+		return b.truncateMessage(out), opts, nil
+	}
+
+	out = strings.TrimSpace(out) + fmt.Sprintf("\n\n<i>Page %d of %d, %d alerts</i>", page+1, len(pages), len(alerts))
+
+	opts.ReplyMarkup = pageButtons(alertsPageButton, token, page, len(pages))
+
+	return b.truncateMessage(out), opts, nil
+}
+
+// pageButtons returns the buttons to show the previous and next page of a list.
+func pageButtons(pageButton telebot.InlineButton, token string, page, pages int) *telebot.ReplyMarkup {
+	var buttons []telebot.InlineButton
+	if page > 0 {
+		button := pageButton
+		button.Text = "◀️ Previous"
+		button.Data = fmt.Sprintf("%s %d", token, page-1)
+		buttons = append(buttons, button)
+	}
+	if page < pages-1 {
+		button := pageButton
+		button.Text = "Next ▶️"
+		button.Data = fmt.Sprintf("%s %d", token, page+1)
+		buttons = append(buttons, button)
+	}
+	return &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{buttons}}
+}
+
+func (b *Bot) handleAlertsPageButton(c *telebot.Callback) error {
+	args := strings.Fields(c.Data)
+	if len(args) != 2 {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseAlertsOutdated})
+	}
+	q, _ := b.pageQueries.get(args[0])
+	query, ok := q.(alertQuery)
+	if !ok || c.Message == nil || c.Message.Chat == nil || query.chat != c.Message.Chat.ID {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseAlertsOutdated})
+	}
+	page, err := strconv.Atoi(args[1])
+	if err != nil {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseAlertsOutdated})
+	}
+
+	out, opts, err := b.renderAlerts(args[0], query, page)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't list the alerts."})
+	}
+
+	if _, err := b.telegram.Edit(c.Message, out, opts); err != nil {
+		return err
+	}
+	return b.telegram.Respond(c)
+}
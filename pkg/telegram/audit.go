@@ -0,0 +1,370 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	// AuditOK is the outcome of successfully handled commands.
+	AuditOK = "ok"
+	// AuditForbidden is the outcome of commands the sender wasn't allowed to use.
+	AuditForbidden = "forbidden"
+
+	auditDefaultLimit = 10
+	auditMaxLimit     = 30
+	// auditAPIMaxLimit is the most entries served on the API at once, which isn't bound by a message's length.
+	auditAPIMaxLimit = 1000
+	auditPruneEvery  = time.Hour
+	// auditForbiddenEvery is how often forbidden commands of the same user are recorded,
+	// so that strangers writing to the bot can't flood the audit log.
+	auditForbiddenEvery = time.Minute
+	auditDayFormat      = "2006-01-02"
+)
+
+// AuditEntry is a single command or change recorded in the audit log.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	UserID   int       `json:"userId"`
+	Username string    `json:"username,omitempty"`
+	ChatID   int64     `json:"chatId"`
+	Command  string    `json:"command"`
+	Args     string    `json:"args,omitempty"`
+	Outcome  string    `json:"outcome"`
+}
+
+func (e AuditEntry) String() string {
+	sender := strconv.Itoa(e.UserID)
+	if e.UserID == 0 {
+		sender = "bot"
+	} else if e.Username != "" {
+		sender = fmt.Sprintf("@%s (%d)", e.Username, e.UserID)
+	}
+	command := strings.TrimSpace(e.Command + " " + e.Args)
+
+	return fmt.Sprintf("%s %s in %d: %s → %s",
+		e.Time.UTC().Format("2006-01-02 15:04:05"),
+		sender, e.ChatID, command, e.Outcome,
+	)
+}
+
+// AuditStore appends audit entries to a libkv store backend.
+// Entries are stored in a bucket per day, so that the latest entries can be
+// listed and old entries pruned without reading the whole audit log.
+type AuditStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+
+	mu      sync.Mutex
+	lastDay string
+}
+
+// NewAuditStore stores the audit log in the provided kv backend.
+func NewAuditStore(kv store.Store, storeKeyPrefix string) (*AuditStore, error) {
+	return &AuditStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+func (s *AuditStore) daysKey() string {
+	return s.storeKeyPrefix + "/days"
+}
+
+func (s *AuditStore) dayKey(day string) string {
+	return fmt.Sprintf("%s/%s", s.storeKeyPrefix, day)
+}
+
+// days returns the days with entries, oldest first.
+func (s *AuditStore) days() ([]string, error) {
+	kv, err := s.kv.Get(s.daysKey())
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var days []string
+	err = json.Unmarshal(kv.Value, &days)
+	return days, err
+}
+
+func (s *AuditStore) putDays(days []string) error {
+	b, err := json.Marshal(days)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.daysKey(), b, nil)
+}
+
+// Append an entry to the audit log.
+func (s *AuditStore) Append(e AuditEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := e.Time.UTC().Format(auditDayFormat)
+	if day != s.lastDay {
+		days, err := s.days()
+		if err != nil {
+			return err
+		}
+		if i := sort.SearchStrings(days, day); i == len(days) || days[i] != day {
+			days = append(days, "")
+			copy(days[i+1:], days[i:])
+			days[i] = day
+			if err := s.putDays(days); err != nil {
+				return err
+			}
+		}
+		s.lastDay = day
+	}
+
+	// Zero padded so that the keys are listed in chronological order.
+	key := fmt.Sprintf("%s/%020d", s.dayKey(day), e.Time.UnixNano())
+
+	return s.kv.Put(key, b, nil)
+}
+
+// List the latest entries of the audit log, newest first.
+func (s *AuditStore) List(limit int) ([]AuditEntry, error) {
+	days, err := s.days()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AuditEntry, 0, limit)
+	for d := len(days) - 1; d >= 0 && len(entries) < limit; d-- {
+		kvPairs, err := s.kv.List(s.dayKey(days[d]) + "/")
+		if err != nil {
+			if errors.Is(err, store.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		for i := len(kvPairs) - 1; i >= 0 && len(entries) < limit; i-- {
+			var e AuditEntry
+			if err := json.Unmarshal(kvPairs[i].Value, &e); err != nil {
+				return nil, err
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+// Prune removes the entries from before the time.
+func (s *AuditStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	days, err := s.days()
+	if err != nil {
+		return err
+	}
+
+	cutoff := before.UTC().Format(auditDayFormat)
+	kept := make([]string, 0, len(days))
+	for _, day := range days {
+		switch {
+		case day < cutoff:
+			if err := s.kv.DeleteTree(s.dayKey(day) + "/"); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+				return err
+			}
+		case day == cutoff:
+			kvPairs, err := s.kv.List(s.dayKey(day) + "/")
+			if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+				return err
+			}
+			for _, kv := range kvPairs {
+				nanos, err := strconv.ParseInt(path.Base(kv.Key), 10, 64)
+				if err != nil || nanos >= before.UnixNano() {
+					continue
+				}
+				if err := s.kv.Delete(kv.Key); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+					return err
+				}
+			}
+			kept = append(kept, day)
+		default:
+			kept = append(kept, day)
+		}
+	}
+
+	if len(kept) == len(days) {
+		return nil
+	}
+	s.lastDay = ""
+	return s.putDays(kept)
+}
+
+// HandleAudit returns a HandlerFunc that lists the latest audit entries as JSON
+// to requests with the token as bearer token in their Authorization header.
+// The number of entries can be set with the limit query parameter, up to auditAPIMaxLimit.
+func HandleAudit(audit BotAuditStore, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := auditDefaultLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			var err error
+			limit, err = strconv.Atoi(l)
+			if err != nil || limit < 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"limit must be a positive integer"}`))
+				return
+			}
+			if limit > auditAPIMaxLimit {
+				limit = auditAPIMaxLimit
+			}
+		}
+
+		entries, err := audit.List(limit)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []AuditEntry{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// audit records a command sent to the bot with its outcome.
+func (b *Bot) audit(m *telebot.Message, outcome string) {
+	e := messageAuditEntry(m)
+	e.Outcome = outcome
+	b.auditEntry(e)
+}
+
+// messageAuditEntry returns the audit entry of a command sent to the bot, without an outcome.
+func messageAuditEntry(m *telebot.Message) AuditEntry {
+	return AuditEntry{
+		Time:     time.Now(),
+		UserID:   m.Sender.ID,
+		Username: m.Sender.Username,
+		ChatID:   m.Chat.ID,
+		Command:  strings.Split(m.Text, " ")[0],
+		Args:     m.Payload,
+	}
+}
+
+// auditForbidden records a command the sender wasn't allowed to use,
+// at most once per auditForbiddenEvery for every user.
+func (b *Bot) auditForbidden(e AuditEntry) {
+	b.auditMu.Lock()
+	if last, ok := b.auditForbiddenAt[e.UserID]; ok && e.Time.Sub(last) < auditForbiddenEvery {
+		b.auditMu.Unlock()
+		return
+	}
+	for id, last := range b.auditForbiddenAt {
+		if e.Time.Sub(last) >= auditForbiddenEvery {
+			delete(b.auditForbiddenAt, id)
+		}
+	}
+	b.auditForbiddenAt[e.UserID] = e.Time
+	b.auditMu.Unlock()
+
+	e.Outcome = AuditForbidden
+	b.auditEntry(e)
+}
+
+// auditAction records an action the bot took on its own in the chat, like a reminder.
+func (b *Bot) auditAction(chatID int64, action, args string) {
+	b.auditEntry(AuditEntry{
+		Time:    time.Now(),
+		ChatID:  chatID,
+		Command: "scheduler:" + action,
+		Args:    args,
+		Outcome: AuditOK,
+	})
+}
+
+// auditEntry appends the entry to the audit log, if there is one,
+// and prunes the entries older than the retention every now and then.
+func (b *Bot) auditEntry(e AuditEntry) {
+	if b.auditLog == nil {
+		return
+	}
+	if err := b.auditLog.Append(e); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to append to audit log", "err", err)
+	}
+
+	b.auditMu.Lock()
+	defer b.auditMu.Unlock()
+	if b.auditRetention <= 0 || time.Since(b.auditPrunedAt) < auditPruneEvery {
+		return
+	}
+	b.auditPrunedAt = time.Now()
+	if err := b.auditLog.Prune(time.Now().Add(-b.auditRetention)); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to prune audit log", "err", err)
+	}
+}
+
+func (b *Bot) handleAudit(message *telebot.Message) error {
+	limit := auditDefaultLimit
+	if payload := strings.TrimSpace(message.Payload); payload != "" {
+		var err error
+		limit, err = strconv.Atoi(payload)
+		if err != nil || limit < 1 {
+			_, err = b.telegram.Send(message.Chat, "Usage: "+CommandAudit+" [number of entries]")
+			return err
+		}
+	}
+	if limit > auditMaxLimit {
+		limit = auditMaxLimit
+	}
+
+	if b.auditLog == nil {
+		_, err := b.telegram.Send(message.Chat, "There is no audit log.")
+		return err
+	}
+
+	entries, err := b.auditLog.List(limit)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list audit log", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't read the audit log.")
+		return err
+	}
+
+	if len(entries) == 0 {
+		_, err = b.telegram.Send(message.Chat, "The audit log is empty.")
+		return err
+	}
+
+	out := ""
+	for _, e := range entries {
+		out = out + e.String() + "\n"
+	}
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(out))
+	return err
+}
@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"net/url"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -16,9 +16,9 @@ import (
 	"github.com/oklog/run"
 	"github.com/pkg/errors"
 	"github.com/prometheus/alertmanager/api/v2/models"
-	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
 	"gopkg.in/tucnak/telebot.v2"
 )
 
@@ -29,12 +29,38 @@ const (
 	CommandChats = "/chats"
 	CommandID    = "/id"
 
-	CommandStatus   = "/status"
-	CommandAlerts   = "/alerts"
-	CommandSilences = "/silences"
+	CommandSubscribe   = "/subscribe"
+	CommandUnsubscribe = "/unsubscribe"
+
+	CommandGrant   = "/grant"
+	CommandRevoke  = "/revoke"
+	CommandTrust   = "/trust"
+	CommandUntrust = "/untrust"
+	CommandAudit   = "/audit"
+
+	CommandAck = "/ack"
+
+	CommandOnCall   = "/oncall"
+	CommandOverride = "/override"
+	CommandRotation = "/rotation"
+
+	CommandStatus      = "/status"
+	CommandAlerts      = "/alerts"
+	CommandAlert       = "/alert"
+	CommandSilences    = "/silences"
+	CommandSilence     = "/silence"
+	CommandExtend      = "/extend"
+	CommandMaintenance = "/maintenance"
+	CommandHistory     = "/history"
+	CommandReceiver    = "/receiver"
+	CommandReceivers   = "/receivers"
+	CommandRoutes      = "/routes"
+	CommandInhibitions = "/inhibitions"
+	CommandGroups      = "/groups"
 
 	responseAlertsNotConfigured = "This chat hasn't been setup to receive any alerts yet... 😕\n\n" +
-		"Ask an administrator of the Alertmanager to add a webhook with `/webhooks/telegram/%d` as URL."
+		"Ask an administrator of the Alertmanager to add a webhook with `%s/webhooks/telegram/%d` as URL " +
+		"or an admin of this bot to bind this chat with `" + CommandReceiver + " set <receiver>`."
 
 	responseStartPrivate          = "Hey, %s! I will now keep you up to date!\n" + CommandHelp
 	responseStartPrivateAnonymous = "Hey! I will now keep you up to date!\n" + CommandHelp
@@ -49,9 +75,30 @@ Available commands:
 ` + CommandStop + ` - Unsubscribe for alerts.
 ` + CommandStatus + ` - Print the current status.
 ` + CommandAlerts + ` - List all alerts.
-` + CommandSilences + ` - List all silences.
+` + CommandReceiver + ` - Show or set the receivers whose alerts this chat lists.
+` + CommandReceivers + ` - List the receivers sending to this bot and their chats.
+` + CommandRoutes + ` - Show the routing tree or test where alerts with some labels are sent to.
+` + CommandInhibitions + ` - List the inhibit rules and the alerts they inhibit right now.
+` + CommandGroups + ` - List the alert groups the Alertmanager notifies about.
+` + CommandAlert + ` - Show the details of an alert by its fingerprint.
+` + CommandHistory + ` - List the alerts that fired and resolved recently and how often they fired.
+` + CommandSilences + ` - List the silences, filtered by state, creator or matchers.
+` + CommandSilence + ` - Silence alerts matching the matchers after previewing them.
+` + CommandExtend + ` - Extend a silence by its ID.
+` + CommandMaintenance + ` - List, add or delete scheduled maintenance windows.
+` + CommandAck + ` - Acknowledge a firing alert to take ownership of it.
+` + CommandOnCall + ` - Show who is on call now and next.
+` + CommandOverride + ` - Put somebody else on call for a while.
+` + CommandRotation + ` - List, add or delete on-call rotations.
 ` + CommandChats + ` - List all users and group chats that subscribed.
+` + CommandSubscribe + ` - Subscribe for alerts of a topic.
+` + CommandUnsubscribe + ` - Unsubscribe from alerts of a topic.
 ` + CommandID + ` - Send the senders Telegram ID (works for all Telegram users).
+` + CommandGrant + ` - Grant a user the viewer, operator or admin role.
+` + CommandRevoke + ` - Revoke a user's role.
+` + CommandTrust + ` - Grant all members of this group chat a role.
+` + CommandUntrust + ` - Stop trusting the members of this group chat.
+` + CommandAudit + ` - List the latest commands and changes.
 `
 )
 
@@ -63,6 +110,74 @@ type BotChatStore interface {
 	Remove(*telebot.Chat) error
 }
 
+// BotTopicStore is all the Bot needs to store and read topic subscriptions.
+type BotTopicStore interface {
+	Subscribers(topic string) ([]*telebot.Chat, error)
+	Subscribed(topic string, id telebot.ChatID) (bool, error)
+	Subscribe(topic string, c *telebot.Chat) error
+	Unsubscribe(topic string, c *telebot.Chat) error
+}
+
+// BotRoleStore is all the Bot needs to store and read roles of users or chats.
+type BotRoleStore interface {
+	List() (map[int64]Role, error)
+	Get(id int64) (Role, error)
+	Set(id int64, r Role) error
+	Remove(id int64) error
+}
+
+// BotAuditStore is all the Bot needs to record and read the audit log.
+type BotAuditStore interface {
+	Append(AuditEntry) error
+	List(limit int) ([]AuditEntry, error)
+	Prune(before time.Time) error
+}
+
+// BotNotificationStore is all the Bot needs to track notifications of firing alert groups.
+type BotNotificationStore interface {
+	List() ([]*Notification, error)
+	Get(chatID int64, id string) (*Notification, error)
+	Put(*Notification) error
+	Remove(chatID int64, id string) error
+}
+
+// BotSilenceStore is all the Bot needs to watch silences expiring.
+type BotSilenceStore interface {
+	List() ([]*WatchedSilence, error)
+	Put(*WatchedSilence) error
+	Remove(id string) error
+}
+
+// BotHistoryStore is all the Bot needs to record and read the alert history.
+type BotHistoryStore interface {
+	Append(HistoryEntry) error
+	List(since time.Time) ([]HistoryEntry, error)
+	Prune(before time.Time) error
+}
+
+// BotMaintenanceStore is all the Bot needs to store and read maintenance windows.
+type BotMaintenanceStore interface {
+	List() ([]*MaintenanceWindow, error)
+	Get(id string) (*MaintenanceWindow, error)
+	Put(*MaintenanceWindow) error
+	Remove(id string) error
+}
+
+// BotRotationStore is all the Bot needs to store and read on-call rotations.
+type BotRotationStore interface {
+	List() ([]*Rotation, error)
+	Get(name string) (*Rotation, error)
+	Put(*Rotation) error
+	Remove(name string) error
+}
+
+// BotReceiverStore is all the Bot needs to store and read the receivers chats are bound to.
+type BotReceiverStore interface {
+	Get(id int64) ([]string, error)
+	Put(id int64, receivers []string) error
+	Remove(id int64) error
+}
+
 // ChatNotFoundErr returned by the store if a chat isn't found.
 var ChatNotFoundErr = errors.New("chat not found in store")
 
@@ -70,13 +185,20 @@ type Telebot interface {
 	Start()
 	Stop()
 	Send(to telebot.Recipient, what interface{}, options ...interface{}) (*telebot.Message, error)
+	Edit(msg telebot.Editable, what interface{}, options ...interface{}) (*telebot.Message, error)
+	Respond(c *telebot.Callback, resp ...*telebot.CallbackResponse) error
 	Notify(to telebot.Recipient, action telebot.ChatAction) error
 	Handle(endpoint interface{}, handler interface{})
+	ChatMemberOf(chat *telebot.Chat, user *telebot.User) (*telebot.ChatMember, error)
 }
 
 type Alertmanager interface {
-	ListAlerts(context.Context, string, bool) ([]*types.Alert, error)
-	ListSilences(context.Context) ([]*types.Silence, error)
+	ListAlerts(context.Context, alertmanager.AlertFilter) ([]*alertmanager.Alert, error)
+	ListAlertGroups(context.Context, alertmanager.AlertFilter) ([]*alertmanager.AlertGroup, error)
+	ListSilences(context.Context, alertmanager.SilenceFilter) ([]*types.Silence, error)
+	GetSilence(context.Context, string) (*types.Silence, error)
+	CreateSilence(context.Context, *types.Silence) (string, error)
+	ExpireSilence(context.Context, string) error
 	Status(context.Context) (*models.AlertmanagerStatus, error)
 }
 
@@ -85,15 +207,63 @@ type Bot struct {
 	addr         string
 	admins       []int // must be kept sorted
 	alertmanager Alertmanager
+	auditLog     BotAuditStore
 	templates    *template.Template
 	chats        BotChatStore
+	topics       []string // must be kept sorted
+	topicStore   BotTopicStore
 	logger       log.Logger
-	revision     string
-	startTime    time.Time
+	roles        BotRoleStore
+	rotations    BotRotationStore
+	receivers    BotReceiverStore
+	// externalURL is the URL the Alertmanager reaches the bot's webhooks below, nil if it reaches them directly.
+	externalURL   *url.URL
+	receiverCache receiverCache
+	trusted       BotRoleStore
+	members       *memberCache
+	revision      string
+
+	notifications      BotNotificationStore
+	notificationsMu    sync.Mutex
+	pageQueries        pageQueries
+	silences           BotSilenceStore
+	silencesMu         sync.Mutex
+	silencesWatched    bool
+	silenceReminder    time.Duration
+	silenceWarnAt      int
+	maintenance        BotMaintenanceStore
+	maintenanceMu      sync.Mutex
+	history            BotHistoryStore
+	historyRetention   time.Duration
+	historyPrunedAt    time.Time
+	auditRetention     time.Duration
+	auditPrunedAt      time.Time
+	auditForbiddenAt   map[int]time.Time
+	auditMu            sync.Mutex
+	flaps              map[string]*flapState
+	flapMu             sync.Mutex
+	flapThreshold      int
+	flapWindow         time.Duration
+	dedupWindow        time.Duration
+	dedupSeen          map[string]dedupEntry
+	watchdogAlert      string
+	watchdogTimeout    time.Duration
+	watchdogMu         sync.Mutex
+	watchdogSeen       time.Time
+	watchdogMissing    bool
+	ackReminder        time.Duration
+	notificationMaxAge time.Duration
+	escalationPolicies []EscalationPolicy
+	schedulerInterval  time.Duration
+	startTime          time.Time
 
 	telegram Telebot
 
 	commandEvents func(command string)
+	// duplicateEvents is called for every duplicate webhook dropped.
+	duplicateEvents func()
+	// webhookEvents is called for every webhook handled.
+	webhookEvents func()
 }
 
 // BotOption passed to NewBot to change the default instance.
@@ -124,6 +294,14 @@ func NewBotWithTelegram(chats BotChatStore, bot Telebot, admin int, opts ...BotO
 		addr:          "127.0.0.1:8080",
 		admins:        []int{admin},
 		commandEvents: func(command string) {},
+
+		schedulerInterval: 30 * time.Second,
+		silenceWarnAt:     10,
+		flaps:             map[string]*flapState{},
+		dedupSeen:         map[string]dedupEntry{},
+		auditForbiddenAt:  map[int]time.Time{},
+		duplicateEvents:   func() {},
+		webhookEvents:     func() {},
 	}
 
 	for _, opt := range opts {
@@ -217,6 +395,218 @@ func WithExtraAdmins(ids ...int) BotOption {
 	}
 }
 
+// WithTopics allows chats to subscribe to the given topics' alerts.
+func WithTopics(store BotTopicStore, topics ...string) BotOption {
+	return func(b *Bot) error {
+		b.topicStore = store
+		b.topics = append(b.topics, topics...)
+		sort.Strings(b.topics)
+		return nil
+	}
+}
+
+// WithRoles reads the roles of users other than the admins from the store.
+func WithRoles(store BotRoleStore) BotOption {
+	return func(b *Bot) error {
+		b.roles = store
+		return nil
+	}
+}
+
+// WithTrustedChats reads the roles of trusted group chats from the store.
+// Members of these chats have at least the chat's role. Membership is
+// verified with Telegram and cached for the given duration.
+func WithTrustedChats(store BotRoleStore, membershipTTL time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.trusted = store
+		b.members = newMemberCache(membershipTTL)
+		return nil
+	}
+}
+
+// WithAuditLog records all commands and changes in the audit log, keeping them for the retention.
+// They are kept forever with a retention of 0.
+func WithAuditLog(store BotAuditStore, retention time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.auditLog = store
+		b.auditRetention = retention
+		return nil
+	}
+}
+
+// WithRotations enables on-call rotations.
+func WithRotations(store BotRotationStore) BotOption {
+	return func(b *Bot) error {
+		b.rotations = store
+		return nil
+	}
+}
+
+// WithMaintenance enables scheduled maintenance windows.
+func WithMaintenance(store BotMaintenanceStore) BotOption {
+	return func(b *Bot) error {
+		b.maintenance = store
+		return nil
+	}
+}
+
+// WithHistory records the alerts the bot is notified about in the history,
+// keeping them for the retention. They are kept forever with a retention of 0.
+func WithHistory(store BotHistoryStore, retention time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.history = store
+		b.historyRetention = retention
+		return nil
+	}
+}
+
+// WithReceivers lets admins bind chats to the receivers whose alerts they list.
+func WithReceivers(store BotReceiverStore) BotOption {
+	return func(b *Bot) error {
+		b.receivers = store
+		return nil
+	}
+}
+
+// WithExternalURL sets the URL the Alertmanager sends webhooks to, if the bot is behind a reverse proxy.
+// Only webhook URLs below it, with the same scheme and host, are taken into account to discover the receivers of chats.
+func WithExternalURL(u *url.URL) BotOption {
+	return func(b *Bot) error {
+		b.externalURL = u
+		return nil
+	}
+}
+
+// webhookBaseURL returns the URL the bot's webhooks are below, to show in messages.
+// It's empty without an external URL, as only the path is known then.
+func (b *Bot) webhookBaseURL() string {
+	if b.externalURL == nil {
+		return ""
+	}
+	return strings.TrimSuffix(b.externalURL.String(), "/")
+}
+
+// WithNotifications tracks the notifications of firing alert groups,
+// so that they can be acknowledged.
+func WithNotifications(store BotNotificationStore) BotOption {
+	return func(b *Bot) error {
+		b.notifications = store
+		return nil
+	}
+}
+
+// WithAckReminder reminds chats of firing alert groups nobody acknowledged after the duration.
+// Reminders are disabled with a duration of 0.
+func WithAckReminder(d time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.ackReminder = d
+		return nil
+	}
+}
+
+// WithNotificationMaxAge forgets notifications of alert groups without a webhook for the duration,
+// which should be longer than the Alertmanager's repeat_interval. They are kept with a duration of 0.
+func WithNotificationMaxAge(d time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.notificationMaxAge = d
+		return nil
+	}
+}
+
+// WithSilences watches silences, to remind of them before they expire.
+func WithSilences(store BotSilenceStore) BotOption {
+	return func(b *Bot) error {
+		b.silences = store
+		return nil
+	}
+}
+
+// WithSilenceReminder reminds of silences the duration before they expire
+// and notifies about silences created outside of the bot. Reminders are disabled with a duration of 0.
+func WithSilenceReminder(d time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.silenceReminder = d
+		return nil
+	}
+}
+
+// WithSilenceWarnThreshold warns before creating silences that silence more than n alerts.
+func WithSilenceWarnThreshold(n int) BotOption {
+	return func(b *Bot) error {
+		b.silenceWarnAt = n
+		return nil
+	}
+}
+
+// WithDeduplication drops webhooks with the same alerts and statuses as the last one
+// received for the alert group within the window. It's disabled with a window of 0.
+func WithDeduplication(window time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.dedupWindow = window
+		return nil
+	}
+}
+
+// WithWebhookEvent sets a func to call whenever a webhook is handled.
+func WithWebhookEvent(callback func()) BotOption {
+	return func(b *Bot) error {
+		b.webhookEvents = callback
+		return nil
+	}
+}
+
+// WithDuplicateEvent sets a func to call whenever a duplicate webhook is dropped.
+func WithDuplicateEvent(callback func()) BotOption {
+	return func(b *Bot) error {
+		b.duplicateEvents = callback
+		return nil
+	}
+}
+
+// WithWatchdog expects the alert to arrive on the watchdog webhook at least once within the timeout
+// and tells the admins when it doesn't. It's disabled with a timeout of 0.
+func WithWatchdog(alertname string, timeout time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.watchdogAlert = alertname
+		b.watchdogTimeout = timeout
+		return nil
+	}
+}
+
+// WithFlapDetection collapses the notifications of alert groups changing status more than threshold times
+// within the window into a single message, until they didn't change for the window. It's disabled with a threshold of 0.
+func WithFlapDetection(threshold int, window time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.flapThreshold = threshold
+		b.flapWindow = window
+		return nil
+	}
+}
+
+// WithEscalationPolicies escalates unacknowledged alert groups to other chats.
+func WithEscalationPolicies(policies ...EscalationPolicy) BotOption {
+	return func(b *Bot) error {
+		for i, p := range policies {
+			if err := p.validate(); err != nil {
+				return errors.Wrapf(err, "escalation policy %d", i)
+			}
+		}
+		b.escalationPolicies = policies
+		return nil
+	}
+}
+
+// WithSchedulerInterval sets how often tracked notifications are checked for reminders and escalations.
+func WithSchedulerInterval(d time.Duration) BotOption {
+	return func(b *Bot) error {
+		if d <= 0 {
+			return fmt.Errorf("scheduler interval must be positive, got %s", d)
+		}
+		b.schedulerInterval = d
+		return nil
+	}
+}
+
 // SendAdminMessage to the admin's ID with a message.
 func (b *Bot) SendAdminMessage(adminID int, message string) {
 	_, _ = b.telegram.Send(&telebot.User{ID: adminID}, message)
@@ -230,14 +620,45 @@ func (b *Bot) isAdminID(id int) bool {
 
 // Run the telegram and listen to messages send to the telegram.
 func (b *Bot) Run(ctx context.Context, webhooks <-chan alertmanager.TelegramWebhook) error {
-	b.telegram.Handle(CommandStart, b.middleware(b.handleStart))
-	b.telegram.Handle(CommandStop, b.middleware(b.handleStop))
-	b.telegram.Handle(CommandHelp, b.middleware(b.handleHelp))
-	b.telegram.Handle(CommandChats, b.middleware(b.handleChats))
-	b.telegram.Handle(CommandID, b.middleware(b.handleID))
-	b.telegram.Handle(CommandStatus, b.middleware(b.handleStatus))
-	b.telegram.Handle(CommandAlerts, b.middleware(b.handleAlerts))
-	b.telegram.Handle(CommandSilences, b.middleware(b.handleSilences))
+	b.telegram.Handle(CommandStart, b.middleware(RoleAdmin, b.handleStart))
+	b.telegram.Handle(CommandStop, b.middleware(RoleAdmin, b.handleStop))
+	b.telegram.Handle(CommandHelp, b.middleware(RoleViewer, b.handleHelp))
+	b.telegram.Handle(CommandChats, b.middleware(RoleAdmin, b.handleChats))
+	b.telegram.Handle(CommandID, b.middleware(RoleNone, b.handleID))
+	b.telegram.Handle(CommandSubscribe, b.middleware(RoleAdmin, b.handleSubscribe))
+	b.telegram.Handle(CommandUnsubscribe, b.middleware(RoleAdmin, b.handleUnsubscribe))
+	b.telegram.Handle(CommandGrant, b.middleware(RoleAdmin, b.handleGrant))
+	b.telegram.Handle(CommandRevoke, b.middleware(RoleAdmin, b.handleRevoke))
+	b.telegram.Handle(CommandTrust, b.middleware(RoleAdmin, b.handleTrust))
+	b.telegram.Handle(CommandUntrust, b.middleware(RoleAdmin, b.handleUntrust))
+	b.telegram.Handle(CommandAudit, b.middleware(RoleAdmin, b.handleAudit))
+	b.telegram.Handle(CommandStatus, b.middleware(RoleViewer, b.handleStatus))
+	b.telegram.Handle(CommandAlerts, b.middleware(RoleViewer, b.handleAlerts))
+	b.telegram.Handle(CommandAlert, b.middleware(RoleViewer, b.handleAlert))
+	b.telegram.Handle(CommandHistory, b.middleware(RoleViewer, b.handleHistory))
+	b.telegram.Handle(CommandSilences, b.middleware(RoleViewer, b.handleSilences))
+	b.telegram.Handle(CommandSilence, b.middleware(RoleOperator, b.handleSilence))
+	b.telegram.Handle(CommandExtend, b.middleware(RoleOperator, b.handleExtend))
+	b.telegram.Handle(CommandMaintenance, b.middleware(RoleOperator, b.handleMaintenance))
+	b.telegram.Handle(CommandReceiver, b.middleware(RoleAdmin, b.handleReceiver))
+	b.telegram.Handle(CommandReceivers, b.middleware(RoleAdmin, b.handleReceivers))
+	b.telegram.Handle(CommandRoutes, b.middleware(RoleViewer, b.handleRoutes))
+	b.telegram.Handle(CommandInhibitions, b.middleware(RoleViewer, b.handleInhibitions))
+	b.telegram.Handle(CommandGroups, b.middleware(RoleViewer, b.handleGroups))
+	b.telegram.Handle(CommandAck, b.middleware(RoleOperator, b.handleAck))
+	b.telegram.Handle(CommandOnCall, b.middleware(RoleViewer, b.handleOnCall))
+	b.telegram.Handle(CommandOverride, b.middleware(RoleOperator, b.handleOverride))
+	b.telegram.Handle(CommandRotation, b.middleware(RoleAdmin, b.handleRotation))
+	b.telegram.Handle(&ackButton, b.callbackMiddleware(ackButton.Unique, RoleOperator, b.handleAckButton))
+	b.telegram.Handle(&alertsPageButton, b.callbackMiddleware(alertsPageButton.Unique, RoleViewer, b.handleAlertsPageButton))
+	b.telegram.Handle(&silenceButton, b.callbackMiddleware(silenceButton.Unique, RoleOperator, b.handleSilenceButton))
+	b.telegram.Handle(&extendButton, b.callbackMiddleware(extendButton.Unique, RoleOperator, b.handleExtendButton))
+	b.telegram.Handle(&silenceConfirmButton, b.callbackMiddleware(silenceConfirmButton.Unique, RoleOperator, b.handleSilenceConfirmButton))
+	b.telegram.Handle(&lapseButton, b.callbackMiddleware(lapseButton.Unique, RoleOperator, b.handleLapseButton))
+	b.telegram.Handle(&silencesPageButton, b.callbackMiddleware(silencesPageButton.Unique, RoleViewer, b.handleSilencesPageButton))
+
+	// The watchdog alert has the whole timeout to arrive after starting.
+	b.watchdogSeen = time.Now()
 
 	var gr run.Group
 	{
@@ -246,6 +667,14 @@ func (b *Bot) Run(ctx context.Context, webhooks <-chan alertmanager.TelegramWebh
 		}, func(err error) {
 		})
 	}
+	if (b.notifications != nil && (b.ackReminder > 0 || len(b.escalationPolicies) > 0 || b.notificationMaxAge > 0)) || (b.silences != nil && b.silenceReminder > 0) || b.maintenance != nil || b.flapThreshold > 0 || b.watchdogTimeout > 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runScheduler(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
 	{
 		gr.Add(func() error {
 			b.telegram.Start()
@@ -258,58 +687,170 @@ func (b *Bot) Run(ctx context.Context, webhooks <-chan alertmanager.TelegramWebh
 	return gr.Run()
 }
 
-// sendWebhook sends messages received via webhook to all subscribed chats.
+// sendWebhook handles the webhooks received until the context is done.
 func (b *Bot) sendWebhook(ctx context.Context, webhooks <-chan alertmanager.TelegramWebhook) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case w := <-webhooks:
-			chat, err := b.chats.Get(telebot.ChatID(w.ChatID))
-			if err != nil {
-				if errors.Is(err, ChatNotFoundErr) {
-					level.Warn(b.logger).Log("msg", "chat is not subscribed for alerts", "chat_id", w.ChatID, "err", err)
-					continue
-				}
-				return err
-			}
+			b.handleWebhook(w)
+			b.webhookEvents()
+		}
+	}
+}
 
-			data := &template.Data{
-				Receiver:          w.Message.Receiver,
-				Status:            w.Message.Status,
-				Alerts:            w.Message.Alerts,
-				GroupLabels:       w.Message.GroupLabels,
-				CommonLabels:      w.Message.CommonLabels,
-				CommonAnnotations: w.Message.CommonAnnotations,
-				ExternalURL:       w.Message.ExternalURL,
-			}
+// handleWebhook sends the messages of a webhook to the chats returned by webhookChats.
+func (b *Bot) handleWebhook(w alertmanager.TelegramWebhook) {
+	if w.Watchdog {
+		b.watchdogWebhook(w.Message)
+		return
+	}
+	if b.duplicate(w) {
+		return
+	}
+	b.recordHistory(w.Message)
 
-			out, err := b.templates.ExecuteHTMLString(`{{ template "telegram.default" . }}`, data)
-			if err != nil {
-				level.Warn(b.logger).Log("msg", "failed to template alerts", "err", err)
-				continue
+	chats, err := b.webhookChats(w)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get chats for webhook", "err", err)
+		return
+	}
+	if len(chats) == 0 {
+		return
+	}
+	if b.flapping(chats, w.Message) {
+		// The notification is collapsed, but an alert group that's not firing anymore isn't reminded of or escalated.
+		if w.Message.Status != string(model.AlertFiring) {
+			for _, chat := range chats {
+				b.trackNotification(chat, nil, w.Message, "")
 			}
+		}
+		return
+	}
+
+	data := &template.Data{
+		Receiver:          w.Message.Receiver,
+		Status:            w.Message.Status,
+		Alerts:            w.Message.Alerts,
+		GroupLabels:       w.Message.GroupLabels,
+		CommonLabels:      w.Message.CommonLabels,
+		CommonAnnotations: w.Message.CommonAnnotations,
+		ExternalURL:       w.Message.ExternalURL,
+	}
 
-			_, err = b.telegram.Send(chat, b.truncateMessage(out), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
-			if err != nil {
-				level.Warn(b.logger).Log("msg", "failed to send message with alerts", "err", err)
-				continue
+	out, err := b.templates.ExecuteHTMLString(`{{ template "telegram.default" . }}`, data)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to template alerts", "err", err)
+		return
+	}
+
+	out = b.truncateMessage(out)
+	for _, chat := range chats {
+		sent, err := b.telegram.Send(chat, out, b.notificationOptions(w.Message))
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send message with alerts", "chat_id", chat.ID, "err", err)
+			continue
+		}
+		b.trackNotification(chat, sent, w.Message, out)
+	}
+}
+
+// webhookChats returns the chats a webhook should be delivered to.
+func (b *Bot) webhookChats(w alertmanager.TelegramWebhook) ([]*telebot.Chat, error) {
+	if w.Broadcast {
+		chats, err := b.broadcastChats(w.Message.Receiver)
+		if err != nil {
+			return nil, err
+		}
+		if len(chats) == 0 {
+			level.Warn(b.logger).Log("msg", "no subscribed chats are bound to the broadcast receiver", "receiver", w.Message.Receiver)
+		}
+		return chats, nil
+	}
+
+	if w.Topic != "" {
+		if !b.isTopic(w.Topic) {
+			level.Warn(b.logger).Log("msg", "webhook for unknown topic", "topic", w.Topic)
+			return nil, nil
+		}
+		chats, err := b.topicStore.Subscribers(w.Topic)
+		if err != nil {
+			return nil, err
+		}
+		if len(chats) == 0 {
+			level.Warn(b.logger).Log("msg", "no chats are subscribed to topic", "topic", w.Topic)
+		}
+		return chats, nil
+	}
+
+	if w.OnCall != "" {
+		chat, err := b.onCallChat(w.OnCall)
+		if err != nil {
+			return nil, err
+		}
+		if chat == nil {
+			level.Warn(b.logger).Log("msg", "webhook for unknown rotation", "rotation", w.OnCall)
+			return nil, nil
+		}
+		return []*telebot.Chat{chat}, nil
+	}
+
+	chat, err := b.chats.Get(telebot.ChatID(w.ChatID))
+	if err != nil {
+		if errors.Is(err, ChatNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "chat is not subscribed for alerts", "chat_id", w.ChatID, "err", err)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return []*telebot.Chat{chat}, nil
+}
+
+// broadcastChats returns the subscribed chats bound to the receiver with /receiver.
+func (b *Bot) broadcastChats(receiver string) ([]*telebot.Chat, error) {
+	if b.receivers == nil {
+		return nil, nil
+	}
+
+	chats, err := b.chats.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var bound []*telebot.Chat
+	for _, chat := range chats {
+		receivers, err := b.receivers.Get(chat.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range receivers {
+			if r == receiver {
+				bound = append(bound, chat)
+				break
 			}
 		}
 	}
+	return bound, nil
 }
 
-func (b *Bot) middleware(next func(*telebot.Message) error) func(*telebot.Message) {
+// middleware only calls next if the sender has at least the given role.
+func (b *Bot) middleware(role Role, next func(*telebot.Message) error) func(*telebot.Message) {
 	return func(m *telebot.Message) {
 		if m.IsService() {
 			return
 		}
-		if !b.isAdminID(m.Sender.ID) && m.Text != CommandID {
+		if r := b.role(m.Chat, m.Sender); r < role {
 			level.Info(b.logger).Log(
 				"msg", "dropping message from forbidden sender",
 				"sender_id", m.Sender.ID,
 				"sender_username", m.Sender.Username,
 			)
+			if r > RoleNone {
+				_, _ = b.telegram.Send(m.Chat, responseForbidden)
+			}
+			b.auditForbidden(messageAuditEntry(m))
 			return
 		}
 
@@ -317,9 +858,50 @@ func (b *Bot) middleware(next func(*telebot.Message) error) func(*telebot.Messag
 		b.commandEvents(command)
 
 		level.Debug(b.logger).Log("msg", "message received", "text", m.Text)
+		outcome := AuditOK
 		if err := next(m); err != nil {
 			level.Warn(b.logger).Log("msg", "failed to handle command", "err", err)
+			outcome = "error: " + err.Error()
+		}
+		b.audit(m, outcome)
+	}
+}
+
+// callbackMiddleware only calls next if the sender of the button press has at least the given role.
+func (b *Bot) callbackMiddleware(name string, role Role, next func(*telebot.Callback) error) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		if c.Message == nil {
+			return
+		}
+
+		entry := AuditEntry{
+			Time:     time.Now(),
+			UserID:   c.Sender.ID,
+			Username: c.Sender.Username,
+			ChatID:   c.Message.Chat.ID,
+			Command:  "button:" + name,
+			Args:     c.Data,
 		}
+
+		if r := b.role(c.Message.Chat, c.Sender); r < role {
+			level.Info(b.logger).Log(
+				"msg", "dropping button press from forbidden sender",
+				"sender_id", c.Sender.ID,
+				"sender_username", c.Sender.Username,
+			)
+			_ = b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseForbidden})
+			b.auditForbidden(entry)
+			return
+		}
+
+		b.commandEvents("button:" + name)
+
+		entry.Outcome = AuditOK
+		if err := next(c); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to handle button press", "err", err)
+			entry.Outcome = "error: " + err.Error()
+		}
+		b.auditEntry(entry)
 	}
 }
 
@@ -437,95 +1019,46 @@ func (b *Bot) handleStatus(message *telebot.Message) error {
 }
 
 func (b *Bot) handleAlerts(message *telebot.Message) error {
-	status, err := b.alertmanager.Status(context.TODO())
+	query, err := parseAlertQuery(message.Payload)
 	if err != nil {
-		level.Warn(b.logger).Log("msg", "failed to get status with config", "err", err)
-		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alerts... %v", err))
-		return err
-	}
-
-	receiver, err := receiverFromConfig(*status.Config.Original, message.Chat.ID)
-	if err != nil || receiver == "" {
-		_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseAlertsNotConfigured, message.Chat.ID), &telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%v\n%s", err, responseAlertsUsage))
 		return err
 	}
 
-	silenced := false
-	if strings.Contains(message.Payload, "silenced") {
-		silenced = true
+	if !query.all {
+		receivers, _, err := b.chatReceivers(context.TODO(), message.Chat.ID)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get receivers of chat", "err", err)
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alerts... %v", err))
+			return err
+		}
+		if len(receivers) == 0 {
+			_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseAlertsNotConfigured, b.webhookBaseURL(), message.Chat.ID), &telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
+			return err
+		}
+		query.receiver = receiversRegexp(receivers)
 	}
+	query.chat = message.Chat.ID
 
-	alerts, err := b.alertmanager.ListAlerts(context.TODO(), receiver, silenced)
+	token := b.pageQueries.add(query)
+	out, opts, err := b.renderAlerts(token, query, 0)
 	if err != nil {
 		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
 		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alerts... %v", err))
 		return err
 	}
 
-	if len(alerts) == 0 {
-		_, err = b.telegram.Send(message.Chat, "No alerts right now! 🎉")
-		return err
-	}
-
-	out, err := b.tmplAlerts(alerts...)
-	if err != nil {
-		level.Warn(b.logger).Log("msg", "failed to template alerts", "err", err)
-		return nil
-	}
-
-	_, err = b.telegram.Send(message.Chat, b.truncateMessage(out), &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	_, err = b.telegram.Send(message.Chat, out, opts)
 	return err
 }
 
-func receiverFromConfig(c string, id int64) (string, error) {
-	if c == "" {
-		return "", fmt.Errorf("config is empty")
-	}
-
-	config, err := config.Load(c)
-	if err != nil {
-		return "", err
-	}
-
-	for _, receiver := range config.Receivers {
-		for _, webhook := range receiver.WebhookConfigs {
-			path := webhook.URL.Path
-			if strings.HasPrefix(path, "/webhooks/telegram/") {
-				parseID, err := strconv.ParseInt(strings.TrimPrefix(path, "/webhooks/telegram/"), 10, 64)
-				if err != nil {
-					return "", err
-				}
-				if parseID == id {
-					return receiver.Name, nil
-				}
-			}
-		}
-	}
-
-	return "", nil
-}
-
-func (b *Bot) handleSilences(message *telebot.Message) error {
-	silences, err := b.alertmanager.ListSilences(context.TODO())
-	if err != nil {
-		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list silences... %v", err))
-		return err
-	}
-
-	if len(silences) == 0 {
-		_, err = b.telegram.Send(message.Chat, "No silences right now.")
-		return err
-	}
-
-	var out string
-	for _, silence := range silences {
-		out = out + alertmanager.SilenceMessage(silence) + "\n"
+// typesAlerts returns the alerts as types.Alert for templating.
+func typesAlerts(alerts []*alertmanager.Alert) []*types.Alert {
+	ta := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		ta = append(ta, &a.Alert)
 	}
-
-	_, err = b.telegram.Send(message.Chat, out, &telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
-	return err
+	return ta
 }
 
 func (b *Bot) tmplAlerts(alerts ...*types.Alert) (string, error) {
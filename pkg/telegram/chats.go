@@ -24,6 +24,9 @@ func NewChatStore(kv store.Store, storeKeyPrefix string) (*ChatStore, error) {
 func (s *ChatStore) List() ([]*telebot.Chat, error) {
 	kvPairs, err := s.kv.List(s.storeKeyPrefix)
 	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
@@ -0,0 +1,60 @@
+package telegram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+)
+
+// dedupEntry is the last webhook received for a target and alert group.
+type dedupEntry struct {
+	alerts string
+	at     time.Time
+}
+
+// duplicate returns true if the webhook has the same alerts and statuses as the last one
+// received for its target and alert group within the deduplication window,
+// like from both replicas of an Alertmanager HA pair.
+// A group firing again after it resolved isn't a duplicate, as the webhook in between differs.
+func (b *Bot) duplicate(w alertmanager.TelegramWebhook) bool {
+	if b.dedupWindow <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	for key, last := range b.dedupSeen {
+		if now.Sub(last.at) >= b.dedupWindow {
+			delete(b.dedupSeen, key)
+		}
+	}
+
+	key, alerts := dedupKey(w)
+	if last, ok := b.dedupSeen[key]; ok && last.alerts == alerts {
+		b.duplicateEvents()
+		level.Debug(b.logger).Log("msg", "dropped duplicate notification", "group", groupID(w.Message.GroupKey))
+		return true
+	}
+	b.dedupSeen[key] = dedupEntry{alerts: alerts, at: now}
+
+	return false
+}
+
+// dedupKey identifies the webhook's target and alert group,
+// and returns its alerts' fingerprints and statuses to compare with the last webhook.
+func dedupKey(w alertmanager.TelegramWebhook) (string, string) {
+	var alerts []string
+	if w.Message.Data != nil {
+		alerts = make([]string, 0, len(w.Message.Alerts))
+		for _, a := range w.Message.Alerts {
+			alerts = append(alerts, a.Fingerprint+":"+a.Status)
+		}
+	}
+	sort.Strings(alerts)
+
+	key := fmt.Sprintf("%d/%t/%s/%s/%s", w.ChatID, w.Broadcast, w.Topic, w.OnCall, w.Message.GroupKey)
+	return key, strings.Join(alerts, ",")
+}
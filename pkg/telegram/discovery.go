@@ -0,0 +1,274 @@
+package telegram
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	responseReceiversNone = "No receiver of the Alertmanager sends to this bot.\n" +
+		"Its webhooks need URLs like %s/webhooks/telegram/<chat-id>."
+	responseReceivers = "Receivers sending to this bot:\n%s"
+)
+
+// receiversConfig is the part of the Alertmanager's config needed to discover receivers.
+// It's parsed leniently, so that configs of newer Alertmanagers work too.
+type receiversConfig struct {
+	Receivers []struct {
+		Name           string `yaml:"name"`
+		WebhookConfigs []struct {
+			URL     string `yaml:"url"`
+			URLFile string `yaml:"url_file"`
+		} `yaml:"webhook_configs"`
+	} `yaml:"receivers"`
+}
+
+// receiverMap maps the bot's webhooks to the Alertmanager receivers sending to them.
+type receiverMap struct {
+	// Names of all receivers, sorted.
+	Names     []string
+	Chats     map[int64][]string
+	Broadcast []string
+	Topics    map[string][]string
+	OnCall    map[string][]string
+}
+
+// receiverCache keeps the receiver map of the last Alertmanager config seen.
+type receiverCache struct {
+	mu   sync.Mutex
+	hash [sha256.Size]byte
+	m    *receiverMap
+}
+
+// discoverReceivers parses the Alertmanager config and maps the webhook URLs of the bot to their receivers.
+// With an external URL only webhook URLs below it match, without one any URL whose path starts with /webhooks/.
+func discoverReceivers(original string, external *url.URL) (*receiverMap, error) {
+	if original == "" {
+		return nil, fmt.Errorf("config is empty")
+	}
+
+	var c receiversConfig
+	if err := yaml.Unmarshal([]byte(original), &c); err != nil {
+		return nil, err
+	}
+
+	m := &receiverMap{
+		Chats:  map[int64][]string{},
+		Topics: map[string][]string{},
+		OnCall: map[string][]string{},
+	}
+	for _, r := range c.Receivers {
+		m.Names = append(m.Names, r.Name)
+
+		for _, wc := range r.WebhookConfigs {
+			u := wc.URL
+			if u == "" && wc.URLFile != "" {
+				// The file is only readable if it's shared with the bot.
+				content, err := ioutil.ReadFile(wc.URLFile)
+				if err != nil {
+					continue
+				}
+				u = strings.TrimSpace(string(content))
+			}
+
+			kind, name, ok := webhookTarget(u, external)
+			if !ok {
+				continue
+			}
+			switch kind {
+			case "telegram":
+				if name == "broadcast" {
+					m.Broadcast = appendReceiver(m.Broadcast, r.Name)
+					continue
+				}
+				id, err := strconv.ParseInt(name, 10, 64)
+				if err != nil {
+					continue
+				}
+				m.Chats[id] = appendReceiver(m.Chats[id], r.Name)
+			case "topic":
+				m.Topics[name] = appendReceiver(m.Topics[name], r.Name)
+			case "oncall":
+				m.OnCall[name] = appendReceiver(m.OnCall[name], r.Name)
+			}
+		}
+	}
+	sort.Strings(m.Names)
+
+	return m, nil
+}
+
+// webhookTarget returns the kind of the bot's webhook a URL points to and the name of its target,
+// like telegram and the chat ID for /webhooks/telegram/123.
+func webhookTarget(rawURL string, external *url.URL) (kind, name string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", false
+	}
+
+	prefix := "/webhooks/"
+	if external != nil {
+		if !strings.EqualFold(u.Scheme, external.Scheme) || !strings.EqualFold(u.Host, external.Host) {
+			return "", "", false
+		}
+		prefix = strings.TrimSuffix(external.Path, "/") + prefix
+	}
+	if !strings.HasPrefix(u.Path, prefix) {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(u.Path, prefix), "/")
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", false
+	}
+	switch parts[0] {
+	case "telegram", "topic", "oncall":
+		return parts[0], parts[1], true
+	default:
+		return "", "", false
+	}
+}
+
+func appendReceiver(receivers []string, name string) []string {
+	for _, r := range receivers {
+		if r == name {
+			return receivers
+		}
+	}
+	return append(receivers, name)
+}
+
+// receiverMap returns the receiver map of the Alertmanager's current config.
+// It's only discovered again once the config changed.
+func (b *Bot) receiverMap(ctx context.Context) (*receiverMap, error) {
+	status, err := b.alertmanager.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	original := ""
+	if status.Config != nil && status.Config.Original != nil {
+		original = *status.Config.Original
+	}
+
+	hash := sha256.Sum256([]byte(original))
+
+	b.receiverCache.mu.Lock()
+	defer b.receiverCache.mu.Unlock()
+
+	if b.receiverCache.m != nil && b.receiverCache.hash == hash {
+		return b.receiverCache.m, nil
+	}
+
+	m, err := discoverReceivers(original, b.externalURL)
+	if err != nil {
+		return nil, err
+	}
+	b.receiverCache.hash = hash
+	b.receiverCache.m = m
+
+	return m, nil
+}
+
+// discoveredReceivers returns the receivers sending to a chat,
+// directly or through a topic or on-call webhook.
+// Broadcast receivers only send to chats bound to them with /receiver.
+func (b *Bot) discoveredReceivers(m *receiverMap, id int64) []string {
+	var receivers []string
+	add := func(names []string) {
+		for _, name := range names {
+			receivers = appendReceiver(receivers, name)
+		}
+	}
+
+	add(m.Chats[id])
+
+	if b.topicStore != nil {
+		for topic, names := range m.Topics {
+			if ok, err := b.topicStore.Subscribed(topic, telebot.ChatID(id)); err == nil && ok {
+				add(names)
+			}
+		}
+	}
+	for rotation, names := range m.OnCall {
+		if chat, err := b.onCallChat(rotation); err == nil && chat != nil && chat.ID == id {
+			add(names)
+		}
+	}
+
+	sort.Strings(receivers)
+	return receivers
+}
+
+func (b *Bot) handleReceivers(message *telebot.Message) error {
+	m, err := b.receiverMap(context.TODO())
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to discover receivers", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't discover the Alertmanager's receivers.")
+		return err
+	}
+
+	var lines []string
+	ids := make([]int64, 0, len(m.Chats))
+	for id := range m.Chats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		name := b.chatName(id)
+		if name != strconv.FormatInt(id, 10) {
+			name = fmt.Sprintf("%s (%d)", name, id)
+		}
+		lines = append(lines, fmt.Sprintf("Chat %s: %s", name, joinEscaped(m.Chats[id])))
+	}
+	if len(m.Broadcast) > 0 {
+		lines = append(lines, "Subscribed chats bound with "+CommandReceiver+": "+joinEscaped(m.Broadcast))
+	}
+	lines = append(lines, namedReceivers("Topic", m.Topics)...)
+	lines = append(lines, namedReceivers("On-call", m.OnCall)...)
+
+	if len(lines) == 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseReceiversNone, b.webhookBaseURL()))
+		return err
+	}
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseReceivers, strings.Join(lines, "\n")), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+	return err
+}
+
+// namedReceivers lists the receivers of topics or rotations sorted by name.
+func namedReceivers(kind string, m map[string][]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s %s: %s", kind, html.EscapeString(name), joinEscaped(m[name])))
+	}
+	return lines
+}
+
+// joinEscaped joins the names escaped for HTML messages.
+func joinEscaped(names []string) string {
+	escaped := make([]string, 0, len(names))
+	for _, name := range names {
+		escaped = append(escaped, html.EscapeString(name))
+	}
+	return strings.Join(escaped, ", ")
+}
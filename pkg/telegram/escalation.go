@@ -0,0 +1,228 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/pkg/errors"
+	"gopkg.in/tucnak/telebot.v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	responseEscalated = "⬆️ <b>Escalated</b> from %s, nobody acknowledged it for %s.\n\n%s"
+
+	// escalationAttempts is how often a step is tried before it's skipped for the next one,
+	// so that a chat the bot can't send to doesn't stall the policy.
+	escalationAttempts = 3
+)
+
+// EscalationConfig is the file escalation policies are loaded from.
+type EscalationConfig struct {
+	Policies []EscalationPolicy `yaml:"policies"`
+}
+
+// EscalationPolicy escalates alert groups to other chats
+// if nobody acknowledges them in the chat they were delivered to.
+type EscalationPolicy struct {
+	Name string `yaml:"name"`
+	// Chat the alert groups have been delivered to, any chat if 0.
+	Chat int64 `yaml:"chat"`
+	// Labels the alert groups' common labels need to have.
+	Labels map[string]string `yaml:"labels"`
+	// Steps are taken one after another until somebody acknowledges the alert group.
+	Steps []EscalationStep `yaml:"steps"`
+}
+
+// EscalationStep notifies a chat once the alert group is unacknowledged for a while.
+type EscalationStep struct {
+	After time.Duration `yaml:"after"`
+	Chat  int64         `yaml:"chat"`
+}
+
+// LoadEscalationPolicies reads and validates the escalation policies from a YAML file.
+func LoadEscalationPolicies(filename string) ([]EscalationPolicy, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var config EscalationConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, errors.Wrap(err, "failed to parse escalation policies")
+	}
+
+	for i, p := range config.Policies {
+		if err := p.validate(); err != nil {
+			return nil, errors.Wrapf(err, "escalation policy %d", i)
+		}
+	}
+
+	return config.Policies, nil
+}
+
+func (p EscalationPolicy) validate() error {
+	if p.Name == "" {
+		return errors.New("name is missing")
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("%s has no steps", p.Name)
+	}
+
+	var after time.Duration
+	for i, s := range p.Steps {
+		if s.Chat == 0 {
+			return fmt.Errorf("%s: step %d has no chat", p.Name, i)
+		}
+		if s.After <= after {
+			return fmt.Errorf("%s: step %d must be after %s", p.Name, i, after)
+		}
+		after = s.After
+	}
+
+	return nil
+}
+
+// matches returns whether the policy applies to the notification.
+func (p EscalationPolicy) matches(n *Notification) bool {
+	if p.Chat != 0 && p.Chat != n.ChatID {
+		return false
+	}
+	for name, value := range p.Labels {
+		if n.Labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// escalationPolicy returns the first policy that applies to the notification.
+func (b *Bot) escalationPolicy(n *Notification) (EscalationPolicy, bool) {
+	for _, p := range b.escalationPolicies {
+		if p.matches(n) {
+			return p, true
+		}
+	}
+	return EscalationPolicy{}, false
+}
+
+// escalate notifies the next chats of the escalation policies
+// about alert groups nobody acknowledged in time.
+func (b *Bot) escalate() error {
+	b.notificationsMu.Lock()
+	defer b.notificationsMu.Unlock()
+
+	notifications, err := b.notifications.List()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		if n.Acked() || n.Origin != 0 {
+			continue
+		}
+		p, ok := b.escalationPolicy(n)
+		if !ok {
+			continue
+		}
+
+		changed := false
+		for n.Escalations < len(p.Steps) && time.Since(n.SentAt) >= p.Steps[n.Escalations].After {
+			step := p.Steps[n.Escalations]
+			changed = true
+
+			// The step is only taken once the chat got the notification, otherwise it's retried on the next tick
+			// until it failed escalationAttempts times.
+			if err := b.escalateTo(n, step); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to escalate alert group", "policy", p.Name, "chat_id", step.Chat, "err", err)
+				n.EscalationFailures++
+				if n.EscalationFailures < escalationAttempts {
+					break
+				}
+				level.Warn(b.logger).Log("msg", "skipped escalation step", "policy", p.Name, "chat_id", step.Chat, "attempts", n.EscalationFailures)
+				b.auditAction(step.Chat, "escalation-skipped", fmt.Sprintf("%s from %d", p.Name, n.ChatID))
+				n.Escalations++
+				n.EscalationFailures = 0
+				continue
+			}
+			n.Escalations++
+			n.EscalationFailures = 0
+			n.EscalatedTo = append(n.EscalatedTo, step.Chat)
+			b.auditAction(step.Chat, "escalation", fmt.Sprintf("%s from %d", p.Name, n.ChatID))
+
+			level.Info(b.logger).Log(
+				"msg", "alert group escalated",
+				"policy", p.Name,
+				"group_key", n.GroupKey,
+				"from", n.ChatID,
+				"to", step.Chat,
+			)
+		}
+
+		if changed {
+			if err := b.notifications.Put(n); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// escalateTo sends the notification to the step's chat and tracks it there,
+// so that it can be acknowledged in that chat too.
+// Only failing to send returns an error, a notification sent is never retried.
+func (b *Bot) escalateTo(n *Notification, step EscalationStep) error {
+	text := fmt.Sprintf(responseEscalated, b.chatName(n.ChatID), durafmt.Parse(step.After), strings.TrimSpace(n.Text))
+
+	button := ackButton
+	button.Data = n.ID()
+
+	sent, err := b.telegram.Send(&telebot.Chat{ID: step.Chat}, b.truncateMessage(text), &telebot.SendOptions{
+		ParseMode:   telebot.ModeHTML,
+		ReplyMarkup: &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{button}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	escalation := &Notification{
+		GroupKey:     n.GroupKey,
+		ChatID:       step.Chat,
+		Text:         text,
+		Fingerprints: n.Fingerprints,
+		Labels:       n.Labels,
+		SentAt:       time.Now(),
+		Origin:       n.ChatID,
+	}
+	if sent != nil {
+		escalation.MessageID = sent.ID
+	}
+
+	if err := b.notifications.Put(escalation); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to track escalated notification", "chat_id", step.Chat, "err", err)
+	}
+	return nil
+}
+
+// chatName returns the name of a subscribed chat, falling back to its ID.
+func (b *Bot) chatName(id int64) string {
+	chat, err := b.chats.Get(telebot.ChatID(id))
+	if err != nil || chat == nil {
+		return strconv.FormatInt(id, 10)
+	}
+	switch {
+	case chat.Title != "":
+		return html.EscapeString(chat.Title)
+	case chat.Username != "":
+		return "@" + html.EscapeString(chat.Username)
+	default:
+		return strconv.FormatInt(id, 10)
+	}
+}
@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseFlapping = "🔀 <b>%s</b> is flapping, it changed %d times in the last %s and is %s now.\n" +
+		"I'll only update this message until it's stable again."
+	responseFlappingStable = "✅ <b>%s</b> stopped flapping and is %s now.\n" +
+		"I'll notify about it as usual again."
+)
+
+// flapState tracks the status changes of an alert group to detect it flapping.
+type flapState struct {
+	name    string
+	status  string
+	changes []time.Time
+	// messages are the flapping messages sent by chat, while the alert group is flapping.
+	messages map[int64]*telebot.StoredMessage
+	text     string
+}
+
+// flapping records the status of the alert group and returns true
+// if the alert group is flapping and the notification was collapsed into its flapping message.
+func (b *Bot) flapping(chats []*telebot.Chat, m webhook.Message) bool {
+	if b.flapThreshold <= 0 {
+		return false
+	}
+
+	b.flapMu.Lock()
+	defer b.flapMu.Unlock()
+
+	now := time.Now()
+	state, ok := b.flaps[m.GroupKey]
+	if !ok {
+		state = &flapState{}
+		b.flaps[m.GroupKey] = state
+	}
+	state.name = flapName(m)
+	if state.status != m.Status {
+		state.status = m.Status
+		state.changes = append(state.changes, now)
+	}
+	state.changes = recentChanges(state.changes, now.Add(-b.flapWindow))
+
+	if state.messages == nil && len(state.changes) <= b.flapThreshold {
+		return false
+	}
+
+	text := fmt.Sprintf(responseFlapping, html.EscapeString(state.name), len(state.changes), model.Duration(b.flapWindow), m.Status)
+	if state.messages == nil {
+		state.messages = map[int64]*telebot.StoredMessage{}
+		level.Info(b.logger).Log("msg", "alert group flapping", "group", groupID(m.GroupKey), "changes", len(state.changes))
+	}
+	if text == state.text {
+		return true
+	}
+	state.text = text
+
+	for _, chat := range chats {
+		if sent, ok := state.messages[chat.ID]; ok {
+			if _, err := b.telegram.Edit(sent, text, &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to update flapping message", "chat_id", chat.ID, "err", err)
+			}
+			continue
+		}
+		sent, err := b.telegram.Send(chat, text, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send flapping message", "chat_id", chat.ID, "err", err)
+			continue
+		}
+		state.messages[chat.ID] = &telebot.StoredMessage{MessageID: strconv.Itoa(sent.ID), ChatID: chat.ID}
+	}
+
+	return true
+}
+
+// settleFlapping updates the messages of alert groups that didn't change for the flap window
+// to tell they're stable again and forgets these alert groups.
+func (b *Bot) settleFlapping() {
+	b.flapMu.Lock()
+	defer b.flapMu.Unlock()
+
+	now := time.Now()
+	for key, state := range b.flaps {
+		state.changes = recentChanges(state.changes, now.Add(-b.flapWindow))
+		if len(state.changes) > 0 {
+			continue
+		}
+		delete(b.flaps, key)
+		if state.messages == nil {
+			continue
+		}
+
+		text := fmt.Sprintf(responseFlappingStable, html.EscapeString(state.name), state.status)
+		for chatID, sent := range state.messages {
+			if _, err := b.telegram.Edit(sent, text, &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to update flapping message", "chat_id", chatID, "err", err)
+			}
+		}
+		level.Info(b.logger).Log("msg", "alert group stopped flapping", "group", groupID(key))
+	}
+}
+
+// recentChanges drops the changes before the time.
+func recentChanges(changes []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(changes) && changes[i].Before(since) {
+		i++
+	}
+	return changes[i:]
+}
+
+// flapName returns the alert name of the alert group or its ID.
+func flapName(m webhook.Message) string {
+	if m.Data != nil {
+		if name, ok := m.CommonLabels[string(model.AlertNameLabel)]; ok {
+			return name
+		}
+	}
+	return groupID(m.GroupKey)
+}
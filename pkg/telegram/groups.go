@@ -0,0 +1,96 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/types"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseGroupsUsage = "Usage: " + CommandGroups + " [all] [matchers...]"
+	responseGroupsNone  = "No alert groups right now! 🎉"
+)
+
+func (b *Bot) handleGroups(message *telebot.Message) error {
+	filter := alertmanager.AlertFilter{Active: true, Silenced: true, Inhibited: true}
+
+	all := false
+	for _, arg := range strings.Fields(message.Payload) {
+		if arg == "all" {
+			all = true
+			continue
+		}
+		if _, err := labels.ParseMatcher(arg); err != nil {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%v\n%s", err, responseGroupsUsage))
+			return err
+		}
+		filter.Matchers = append(filter.Matchers, arg)
+	}
+
+	if !all {
+		receivers, _, err := b.chatReceivers(context.TODO(), message.Chat.ID)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get receivers of chat", "err", err)
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alert groups... %v", err))
+			return err
+		}
+		if len(receivers) == 0 {
+			_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseAlertsNotConfigured, b.webhookBaseURL(), message.Chat.ID), &telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
+			return err
+		}
+		filter.Receiver = receiversRegexp(receivers)
+	}
+
+	groups, err := b.alertmanager.ListAlertGroups(context.TODO(), filter)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alert groups", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alert groups... %v", err))
+		return err
+	}
+	if len(groups) == 0 {
+		_, err = b.telegram.Send(message.Chat, responseGroupsNone)
+		return err
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Receiver != groups[j].Receiver {
+			return groups[i].Receiver < groups[j].Receiver
+		}
+		return groups[i].Labels.String() < groups[j].Labels.String()
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>Alert groups:</b> %d\n", len(groups))
+	for _, g := range groups {
+		suppressed := 0
+		for _, a := range g.Alerts {
+			if a.Status.State == types.AlertStateSuppressed {
+				suppressed++
+			}
+		}
+
+		fmt.Fprintf(&sb, "\n<code>%s</code> → %s\n", html.EscapeString(g.Labels.String()), html.EscapeString(g.Receiver))
+		if len(g.Alerts) == 1 {
+			sb.WriteString("    1 alert")
+		} else {
+			fmt.Fprintf(&sb, "    %d alerts", len(g.Alerts))
+		}
+		if suppressed > 0 {
+			fmt.Fprintf(&sb, ", %d suppressed", suppressed)
+		}
+		sb.WriteString("\n")
+	}
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(sb.String()), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+	return err
+}
@@ -0,0 +1,276 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseHistoryUsage = "Usage: " + CommandHistory + " [matchers...] [since]\n" +
+		"For example: " + CommandHistory + " alertname=NodeDown 3d"
+	responseHistoryEmpty = "Nothing fired or resolved in the last %s."
+
+	historyDefaultSince = 24 * time.Hour
+	// historyStatsPeriod is how far back /history counts how often alerts fired.
+	historyStatsPeriod = 7 * 24 * time.Hour
+	historyMaxEntries  = 20
+	historyPruneEvery  = time.Hour
+)
+
+// HistoryEntry is an alert firing or resolving.
+type HistoryEntry struct {
+	Fingerprint string         `json:"fingerprint"`
+	Labels      model.LabelSet `json:"labels"`
+	Status      string         `json:"status"`
+	StartsAt    time.Time      `json:"startsAt"`
+	EndsAt      time.Time      `json:"endsAt,omitempty"`
+}
+
+// Time returns when the alert started firing or when it was resolved.
+func (e HistoryEntry) Time() time.Time {
+	if e.Status == string(model.AlertResolved) {
+		return e.EndsAt
+	}
+	return e.StartsAt
+}
+
+// HistoryStore appends alert transitions to a libkv store backend.
+type HistoryStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewHistoryStore stores the alert history in the provided kv backend.
+func NewHistoryStore(kv store.Store, storeKeyPrefix string) (*HistoryStore, error) {
+	return &HistoryStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+func (s *HistoryStore) key(e HistoryEntry) string {
+	// Zero padded so that the keys are listed in chronological order.
+	// The same transition notified again is written to the same key and only stored once.
+	return fmt.Sprintf("%s/%020d-%s-%s", s.storeKeyPrefix, e.Time().UnixNano(), e.Fingerprint, e.Status)
+}
+
+// Append an entry to the history.
+func (s *HistoryStore) Append(e HistoryEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key(e), b, nil)
+}
+
+// List the entries of the history since the time, oldest first.
+func (s *HistoryStore) List(since time.Time) ([]HistoryEntry, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0, len(kvPairs))
+	for _, kv := range kvPairs {
+		var e HistoryEntry
+		if err := json.Unmarshal(kv.Value, &e); err != nil {
+			return nil, err
+		}
+		if e.Time().Before(since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time().Before(entries[j].Time()) })
+
+	return entries, nil
+}
+
+// Prune removes the entries from before the time.
+func (s *HistoryStore) Prune(before time.Time) error {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	for _, kv := range kvPairs {
+		var e HistoryEntry
+		if err := json.Unmarshal(kv.Value, &e); err != nil {
+			return err
+		}
+		if !e.Time().Before(before) {
+			continue
+		}
+		if err := s.kv.Delete(s.key(e)); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordHistory appends the alerts of the webhook message to the history
+// and prunes the entries older than the retention every now and then.
+func (b *Bot) recordHistory(m webhook.Message) {
+	if b.history == nil || m.Data == nil {
+		return
+	}
+
+	for _, a := range m.Alerts {
+		e := HistoryEntry{
+			Fingerprint: a.Fingerprint,
+			Labels:      make(model.LabelSet, len(a.Labels)),
+			Status:      a.Status,
+			StartsAt:    a.StartsAt,
+			EndsAt:      a.EndsAt,
+		}
+		for name, value := range a.Labels {
+			e.Labels[model.LabelName(name)] = model.LabelValue(value)
+		}
+		if e.Status == string(model.AlertResolved) && e.EndsAt.IsZero() {
+			e.EndsAt = time.Now()
+		}
+		if err := b.history.Append(e); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to append to history", "fingerprint", a.Fingerprint, "err", err)
+		}
+	}
+
+	if b.historyRetention <= 0 || time.Since(b.historyPrunedAt) < historyPruneEvery {
+		return
+	}
+	b.historyPrunedAt = time.Now()
+	if err := b.history.Prune(time.Now().Add(-b.historyRetention)); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to prune history", "err", err)
+	}
+}
+
+// parseHistory parses the matchers and the optional duration to look back of /history.
+func parseHistory(payload string) (labels.Matchers, time.Duration, error) {
+	args := strings.Fields(payload)
+	since := historyDefaultSince
+	if len(args) > 0 {
+		if d, err := model.ParseDuration(args[len(args)-1]); err == nil && d > 0 {
+			since = time.Duration(d)
+			args = args[:len(args)-1]
+		}
+	}
+
+	matchers := make(labels.Matchers, 0, len(args))
+	for _, arg := range args {
+		m, err := labels.ParseMatcher(arg)
+		if err != nil {
+			return nil, 0, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	return matchers, since, nil
+}
+
+func (b *Bot) handleHistory(message *telebot.Message) error {
+	matchers, since, err := parseHistory(message.Payload)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%v\n%s", err, responseHistoryUsage))
+		return err
+	}
+
+	if b.history == nil {
+		_, err := b.telegram.Send(message.Chat, "There is no alert history.")
+		return err
+	}
+
+	now := time.Now()
+	from := now.Add(-historyStatsPeriod)
+	if since > historyStatsPeriod {
+		from = now.Add(-since)
+	}
+	all, err := b.history.List(from)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list history", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't read the alert history.")
+		return err
+	}
+
+	var entries []HistoryEntry
+	fired := map[string]int{}
+	for _, e := range all {
+		if !matchers.Matches(e.Labels) {
+			continue
+		}
+		if e.Status == string(model.AlertFiring) && now.Sub(e.Time()) <= historyStatsPeriod {
+			fired[string(e.Labels[model.AlertNameLabel])]++
+		}
+		if now.Sub(e.Time()) <= since {
+			entries = append(entries, e)
+		}
+	}
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(historyMessage(entries, fired, since)), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// historyMessage lists the latest entries, newest first, followed by how often alerts fired.
+func historyMessage(entries []HistoryEntry, fired map[string]int, since time.Duration) string {
+	var sb strings.Builder
+	if len(entries) == 0 {
+		fmt.Fprintf(&sb, responseHistoryEmpty+"\n", model.Duration(since))
+	} else {
+		fmt.Fprintf(&sb, "<b>History of the last %s:</b>\n", model.Duration(since))
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if len(entries)-i > historyMaxEntries {
+			fmt.Fprintf(&sb, "and %d more\n", i+1)
+			break
+		}
+		e := entries[i]
+		emoji := "🔥"
+		if e.Status == string(model.AlertResolved) {
+			emoji = "✅"
+		}
+		fmt.Fprintf(&sb, "%s %s %s <code>%s</code>\n",
+			e.Time().UTC().Format(onCallTimeFormat), emoji,
+			html.EscapeString(string(e.Labels[model.AlertNameLabel])), html.EscapeString(e.Fingerprint),
+		)
+	}
+
+	if len(fired) == 0 {
+		return sb.String()
+	}
+
+	names := make([]string, 0, len(fired))
+	for name := range fired {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if fired[names[i]] != fired[names[j]] {
+			return fired[names[i]] > fired[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprintf(&sb, "\n<b>Fired in the last %s:</b>\n", model.Duration(historyStatsPeriod))
+	for _, name := range names {
+		times := "times"
+		if fired[name] == 1 {
+			times = "time"
+		}
+		fmt.Fprintf(&sb, "%s: %d %s\n", html.EscapeString(name), fired[name], times)
+	}
+
+	return sb.String()
+}
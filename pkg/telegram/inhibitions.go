@@ -0,0 +1,140 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseInhibitRulesNone     = "There are no inhibit rules."
+	responseInhibitedAlertsNone  = "No alerts are inhibited right now."
+	responseInhibitedAlertSource = "Unknown alert <code>%s</code> inhibits:\n"
+)
+
+func (b *Bot) handleInhibitions(message *telebot.Message) error {
+	status, err := b.alertmanager.Status(context.TODO())
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get status with config", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list inhibitions... %v", err))
+		return err
+	}
+	if status.Config == nil || status.Config.Original == nil {
+		_, err = b.telegram.Send(message.Chat, "failed to list inhibitions... config is empty")
+		return err
+	}
+	c, err := config.Load(*status.Config.Original)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to load the Alertmanager's config", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list inhibitions... %v", err))
+		return err
+	}
+
+	alerts, err := b.alertmanager.ListAlerts(context.TODO(), alertmanager.AlertFilter{
+		Active:      true,
+		Silenced:    true,
+		Inhibited:   true,
+		Unprocessed: true,
+	})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list inhibitions... %v", err))
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Inhibit rules:</b>\n")
+	writeInhibitRules(&sb, c.InhibitRules)
+	sb.WriteString("\n<b>Inhibited alerts:</b>\n")
+	writeInhibitedAlerts(&sb, alerts)
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(sb.String()), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+	return err
+}
+
+// writeInhibitRules writes one line per rule with its source and target matchers.
+func writeInhibitRules(sb *strings.Builder, rules []*config.InhibitRule) {
+	if len(rules) == 0 {
+		sb.WriteString(responseInhibitRulesNone + "\n")
+		return
+	}
+
+	for i, r := range rules {
+		source := ruleMatchers(r.SourceMatch, r.SourceMatchRE, r.SourceMatchers)
+		target := ruleMatchers(r.TargetMatch, r.TargetMatchRE, r.TargetMatchers)
+		fmt.Fprintf(sb, "%d. <code>%s</code> inhibits <code>%s</code>", i+1, html.EscapeString(source.String()), html.EscapeString(target.String()))
+		if len(r.Equal) > 0 {
+			fmt.Fprintf(sb, " with equal %s", html.EscapeString(r.Equal.String()))
+		}
+		sb.WriteString("\n")
+	}
+}
+
+func ruleMatchers(match map[string]string, matchRE config.MatchRegexps, matchers config.Matchers) labels.Matchers {
+	ms := make(labels.Matchers, 0, len(match)+len(matchRE)+len(matchers))
+	for name, value := range match {
+		if m, err := labels.NewMatcher(labels.MatchEqual, name, value); err == nil {
+			ms = append(ms, m)
+		}
+	}
+	for name, re := range matchRE {
+		if m, err := labels.NewMatcher(labels.MatchRegexp, name, re.String()); err == nil {
+			ms = append(ms, m)
+		}
+	}
+	ms = append(ms, matchers...)
+	sort.Sort(ms)
+	return ms
+}
+
+// writeInhibitedAlerts writes the inhibited alerts grouped by the alerts inhibiting them.
+func writeInhibitedAlerts(sb *strings.Builder, alerts []*alertmanager.Alert) {
+	byFingerprint := make(map[string]*alertmanager.Alert, len(alerts))
+	for _, a := range alerts {
+		byFingerprint[a.Fingerprint] = a
+	}
+
+	var sources []string
+	inhibited := map[string][]*alertmanager.Alert{}
+	for _, a := range alerts {
+		for _, fp := range a.Status.InhibitedBy {
+			if _, ok := inhibited[fp]; !ok {
+				sources = append(sources, fp)
+			}
+			inhibited[fp] = append(inhibited[fp], a)
+		}
+	}
+	sort.Strings(sources)
+
+	if len(sources) == 0 {
+		sb.WriteString(responseInhibitedAlertsNone + "\n")
+		return
+	}
+
+	for _, fp := range sources {
+		if source, ok := byFingerprint[fp]; ok {
+			fmt.Fprintf(sb, "%s inhibits:\n", alertLine(source))
+		} else {
+			fmt.Fprintf(sb, responseInhibitedAlertSource, html.EscapeString(fp))
+		}
+		for _, a := range inhibited[fp] {
+			fmt.Fprintf(sb, "    ↳ %s\n", alertLine(a))
+		}
+	}
+}
+
+// alertLine is an alert's name and fingerprint to look it up with /alert.
+func alertLine(a *alertmanager.Alert) string {
+	return fmt.Sprintf("%s <code>%s</code>", html.EscapeString(string(a.Labels[model.AlertNameLabel])), html.EscapeString(a.Fingerprint))
+}
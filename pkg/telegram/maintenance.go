@@ -0,0 +1,402 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseMaintenanceUsage = "Usage: " + CommandMaintenance + " <start> <end> [every:<interval>] <matchers...>\n" +
+		"For example: " + CommandMaintenance + " 2021-03-07T02:00:00Z 2021-03-07T04:00:00Z every:1w cluster=eu1\n" +
+		"Delete a maintenance window with " + CommandMaintenance + " delete <id>."
+	responseMaintenanceNone     = "There are no maintenance windows.\n" + responseMaintenanceUsage
+	responseMaintenanceSaved    = "🔧 Maintenance window <code>%s</code> saved, next from %s to %s."
+	responseMaintenanceDeleted  = "Maintenance window %s deleted."
+	responseMaintenanceExpired  = "Maintenance window %s deleted and its silence expired."
+	responseMaintenanceActive   = "Maintenance window %s deleted, but I can't expire its silence %s, it stays active until %s."
+	responseMaintenanceUnknown  = "There is no maintenance window %s."
+	responseMaintenanceExists   = "The maintenance window <code>%s</code> exists already, delete it first to change it."
+	responseMaintenanceSilenced = "🔧 The maintenance window <code>%s</code> starts %s, I silenced <code>%s</code> until %s."
+
+	// maintenanceLead is how long before a maintenance window its silence is created.
+	maintenanceLead = 10 * time.Minute
+)
+
+// MaintenanceWindow silences alerts matching its matchers from start to end, repeating every interval.
+type MaintenanceWindow struct {
+	ID       string    `json:"id"`
+	Matchers []string  `json:"matchers"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	// Every is the interval the window repeats in, 0 if it doesn't repeat.
+	Every     time.Duration `json:"every,omitempty"`
+	ChatID    int64         `json:"chatId"`
+	UserID    int           `json:"userId"`
+	CreatedBy string        `json:"createdBy"`
+	// SilencedUntil is the end of the latest occurrence silenced in the Alertmanager.
+	SilencedUntil time.Time `json:"silencedUntil,omitempty"`
+	// SilenceID is the ID of the silence of the latest occurrence.
+	SilenceID string `json:"silenceId,omitempty"`
+}
+
+// Next returns the start and end of the first occurrence ending after t.
+// It returns false if a window not repeating is over.
+func (w *MaintenanceWindow) Next(t time.Time) (time.Time, time.Time, bool) {
+	if w.End.After(t) {
+		return w.Start, w.End, true
+	}
+	if w.Every <= 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	shift := (t.Sub(w.End)/w.Every + 1) * w.Every
+	return w.Start.Add(shift), w.End.Add(shift), true
+}
+
+// labelMatchers parses the window's matchers, sorted like the Alertmanager sorts them.
+func (w *MaintenanceWindow) labelMatchers() (labels.Matchers, error) {
+	matchers := make(labels.Matchers, 0, len(w.Matchers))
+	for _, raw := range w.Matchers {
+		m, err := labels.ParseMatcher(raw)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	sort.Sort(matchers)
+	return matchers, nil
+}
+
+// MaintenanceStore writes maintenance windows to a libkv store backend.
+type MaintenanceStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewMaintenanceStore stores maintenance windows in the provided kv backend.
+func NewMaintenanceStore(kv store.Store, storeKeyPrefix string) (*MaintenanceStore, error) {
+	return &MaintenanceStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all maintenance windows saved in the kv backend, sorted by start.
+func (s *MaintenanceStore) List() ([]*MaintenanceWindow, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	windows := make([]*MaintenanceWindow, 0, len(kvPairs))
+	for _, kv := range kvPairs {
+		var w *MaintenanceWindow
+		if err := json.Unmarshal(kv.Value, &w); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+
+	return windows, nil
+}
+
+// Get a maintenance window by its ID, nil if there is none.
+func (s *MaintenanceStore) Get(id string) (*MaintenanceWindow, error) {
+	kv, err := s.kv.Get(s.storeKeyPrefix + "/" + id)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var w *MaintenanceWindow
+	err = json.Unmarshal(kv.Value, &w)
+	return w, err
+}
+
+// Put a maintenance window into the kv backend.
+func (s *MaintenanceStore) Put(w *MaintenanceWindow) error {
+	b, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.storeKeyPrefix+"/"+w.ID, b, nil)
+}
+
+// Remove a maintenance window from the kv backend.
+func (s *MaintenanceStore) Remove(id string) error {
+	err := s.kv.Delete(s.storeKeyPrefix + "/" + id)
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// parseMaintenanceWindow parses the arguments of /maintenance.
+func parseMaintenanceWindow(args []string) (*MaintenanceWindow, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("a start, an end and matchers are required")
+	}
+
+	start, err := time.Parse(time.RFC3339, args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s isn't a valid start", args[0])
+	}
+	end, err := time.Parse(time.RFC3339, args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s isn't a valid end", args[1])
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("the end needs to be after the start")
+	}
+
+	w := &MaintenanceWindow{Start: start, End: end}
+	for _, arg := range args[2:] {
+		if strings.HasPrefix(arg, "every:") {
+			every, err := model.ParseDuration(strings.TrimPrefix(arg, "every:"))
+			if err != nil || time.Duration(every) < end.Sub(start) {
+				return nil, fmt.Errorf("%s needs to be a duration at least as long as the window", arg)
+			}
+			w.Every = time.Duration(every)
+			continue
+		}
+		if _, err := labels.ParseMatcher(arg); err != nil {
+			return nil, err
+		}
+		w.Matchers = append(w.Matchers, arg)
+	}
+	if len(w.Matchers) == 0 {
+		return nil, fmt.Errorf("a start, an end and matchers are required")
+	}
+
+	w.ID = groupID(strings.Join(args, " "))[:8]
+
+	return w, nil
+}
+
+func (b *Bot) handleMaintenance(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) == 0 {
+		return b.sendMaintenanceWindows(message.Chat)
+	}
+
+	if b.maintenance == nil {
+		_, err := b.telegram.Send(message.Chat, "I can't store maintenance windows.")
+		return err
+	}
+
+	// The scheduler writes windows back after silencing them, deleting one in between would be undone.
+	b.maintenanceMu.Lock()
+	defer b.maintenanceMu.Unlock()
+
+	if args[0] == "delete" && len(args) == 2 {
+		w, err := b.maintenance.Get(args[1])
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get maintenance window", "id", args[1], "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't read the maintenance window.")
+			return err
+		}
+		if w == nil {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseMaintenanceUnknown, args[1]))
+			return err
+		}
+		if err := b.maintenance.Remove(w.ID); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to remove maintenance window", "id", w.ID, "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't delete the maintenance window.")
+			return err
+		}
+
+		level.Info(b.logger).Log(
+			"msg", "maintenance window deleted",
+			"id", w.ID,
+			"deleted_by", message.Sender.ID,
+		)
+
+		if w.SilenceID == "" || !w.SilencedUntil.After(time.Now()) {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseMaintenanceDeleted, w.ID))
+			return err
+		}
+		if err := b.alertmanager.ExpireSilence(context.TODO(), w.SilenceID); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to expire maintenance window silence", "id", w.ID, "silence_id", w.SilenceID, "err", err)
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseMaintenanceActive,
+				w.ID, w.SilenceID, w.SilencedUntil.UTC().Format(onCallTimeFormat),
+			))
+			return err
+		}
+		level.Info(b.logger).Log("msg", "maintenance window silence expired", "id", w.ID, "silence_id", w.SilenceID)
+
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseMaintenanceExpired, w.ID))
+		return err
+	}
+
+	w, err := parseMaintenanceWindow(args)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%v\n%s", err, responseMaintenanceUsage))
+		return err
+	}
+	start, end, ok := w.Next(time.Now())
+	if !ok {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The maintenance window is over already.\n%s", responseMaintenanceUsage))
+		return err
+	}
+
+	existing, err := b.maintenance.Get(w.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get maintenance window", "id", w.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't read the maintenance window.")
+		return err
+	}
+	if existing != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseMaintenanceExists, w.ID), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		return err
+	}
+
+	w.ChatID = message.Chat.ID
+	w.UserID = message.Sender.ID
+	w.CreatedBy = displayName(message.Sender)
+
+	if err := b.maintenance.Put(w); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to store maintenance window", "id", w.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't store the maintenance window.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "maintenance window saved",
+		"id", w.ID,
+		"every", model.Duration(w.Every),
+		"saved_by", message.Sender.ID,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseMaintenanceSaved,
+		w.ID, start.UTC().Format(onCallTimeFormat), end.UTC().Format(onCallTimeFormat),
+	), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// sendMaintenanceWindows sends the maintenance windows and their next occurrence to the chat.
+func (b *Bot) sendMaintenanceWindows(chat *telebot.Chat) error {
+	var windows []*MaintenanceWindow
+	if b.maintenance != nil {
+		var err error
+		windows, err = b.maintenance.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list maintenance windows", "err", err)
+			_, err = b.telegram.Send(chat, "I can't list the maintenance windows.")
+			return err
+		}
+	}
+	if len(windows) == 0 {
+		_, err := b.telegram.Send(chat, responseMaintenanceNone)
+		return err
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	sb.WriteString("<b>Maintenance windows:</b>\n")
+	for _, w := range windows {
+		start, end, ok := w.Next(now)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&sb, "<code>%s</code> <code>%s</code>\n    %s to %s",
+			w.ID, html.EscapeString(strings.Join(w.Matchers, " ")),
+			start.UTC().Format(onCallTimeFormat), end.UTC().Format(onCallTimeFormat),
+		)
+		if w.Every > 0 {
+			fmt.Fprintf(&sb, ", every %s", model.Duration(w.Every))
+		}
+		fmt.Fprintf(&sb, " by %s\n", html.EscapeString(w.CreatedBy))
+	}
+
+	_, err := b.telegram.Send(chat, b.truncateMessage(sb.String()), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// silenceMaintenanceWindows creates the silences of maintenance windows starting soon
+// and removes windows that are over.
+func (b *Bot) silenceMaintenanceWindows() error {
+	b.maintenanceMu.Lock()
+	defer b.maintenanceMu.Unlock()
+
+	windows, err := b.maintenance.List()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, w := range windows {
+		start, end, ok := w.Next(now)
+		if !ok {
+			if err := b.maintenance.Remove(w.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		if start.Sub(now) > maintenanceLead || !w.SilencedUntil.Before(end) {
+			continue
+		}
+
+		matchers, err := w.labelMatchers()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to parse maintenance window matchers", "id", w.ID, "err", err)
+			continue
+		}
+
+		startsAt := start
+		if startsAt.Before(now) {
+			startsAt = now
+		}
+		id, err := b.createSilence(context.TODO(), &types.Silence{
+			Matchers:  matchers,
+			StartsAt:  startsAt,
+			EndsAt:    end,
+			CreatedBy: w.CreatedBy,
+			Comment:   "Maintenance window " + w.ID,
+		}, &telebot.Chat{ID: w.ChatID}, &telebot.User{ID: w.UserID})
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to silence maintenance window", "id", w.ID, "err", err)
+			continue
+		}
+
+		w.SilencedUntil = end
+		w.SilenceID = id
+		if err := b.maintenance.Put(w); err != nil {
+			return err
+		}
+
+		level.Info(b.logger).Log(
+			"msg", "maintenance window silenced",
+			"id", w.ID,
+			"silence_id", id,
+		)
+		b.auditAction(w.ChatID, "maintenance-silence", w.ID+" "+id)
+
+		starts := "now"
+		if start.After(now) {
+			starts = "in " + durafmt.Parse(start.Sub(now).Round(time.Minute)).String()
+		}
+		_, err = b.telegram.Send(&telebot.Chat{ID: w.ChatID}, fmt.Sprintf(responseMaintenanceSilenced,
+			w.ID, starts, html.EscapeString(matchers.String()), end.UTC().Format(onCallTimeFormat),
+		), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send maintenance notification", "chat_id", w.ChatID, "err", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,396 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseRotationUsage = "Usage: " + CommandRotation + " <name> <length> <first handoff> <user-id>...\n" +
+		"For example: " + CommandRotation + " ops 1w 2021-03-01T09:00:00Z 123 456\n" +
+		"Delete a rotation with " + CommandRotation + " delete <name>."
+	responseOverrideUsage = "Usage: " + CommandOverride + " <rotation> <user-id> [duration]"
+	responseRotationsNone = "There are no on-call rotations yet.\n" + CommandRotation
+	responseRotationUnkn  = "There is no rotation called %s."
+	responseRotationSaved = "Rotation %s saved, %s is on call now."
+	responseRotationGone  = "Rotation %s deleted."
+	responseOverridden    = "%s is on call for %s until %s."
+
+	onCallTimeFormat = "2006-01-02 15:04 MST"
+)
+
+// Rotation hands the on-call duty from one participant to the next after every shift.
+type Rotation struct {
+	Name string `json:"name"`
+	// Participants are the Telegram user IDs taking turns, in order.
+	Participants []int `json:"participants"`
+	// Handoff is the start of the first participant's first shift.
+	Handoff time.Time `json:"handoff"`
+	// Length of every shift.
+	Length time.Duration `json:"length"`
+	// Overrides take precedence over the shifts, the latest one first.
+	Overrides []Override `json:"overrides,omitempty"`
+}
+
+// Override puts a user on call for a while, regardless of the rotation's shifts.
+type Override struct {
+	User  int       `json:"user"`
+	From  time.Time `json:"from"`
+	Until time.Time `json:"until"`
+}
+
+// shift returns the index of the shift at t with its start and end.
+func (r *Rotation) shift(t time.Time) (int64, time.Time, time.Time) {
+	n := int64(t.Sub(r.Handoff) / r.Length)
+	if t.Before(r.Handoff) && t.Sub(r.Handoff)%r.Length != 0 {
+		n-- // Round towards the past for shifts before the first handoff.
+	}
+	start := r.Handoff.Add(time.Duration(n) * r.Length)
+	return n, start, start.Add(r.Length)
+}
+
+// OnCall returns the user on call at t.
+func (r *Rotation) OnCall(t time.Time) int {
+	if o, ok := r.override(t); ok {
+		return o.User
+	}
+
+	n, _, _ := r.shift(t)
+	i := n % int64(len(r.Participants))
+	if i < 0 {
+		i += int64(len(r.Participants))
+	}
+	return r.Participants[i]
+}
+
+func (r *Rotation) override(t time.Time) (Override, bool) {
+	for i := len(r.Overrides) - 1; i >= 0; i-- {
+		o := r.Overrides[i]
+		if !t.Before(o.From) && t.Before(o.Until) {
+			return o, true
+		}
+	}
+	return Override{}, false
+}
+
+// Until returns when the user on call at t hands over to the next one.
+func (r *Rotation) Until(t time.Time) time.Time {
+	_, _, until := r.shift(t)
+	if o, ok := r.override(t); ok && o.Until.Before(until) {
+		until = o.Until
+	}
+	for _, o := range r.Overrides {
+		if o.From.After(t) && o.From.Before(until) {
+			until = o.From
+		}
+	}
+	return until
+}
+
+// RotationStore writes on-call rotations to a libkv store backend.
+type RotationStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewRotationStore stores on-call rotations in the provided kv backend.
+func NewRotationStore(kv store.Store, storeKeyPrefix string) (*RotationStore, error) {
+	return &RotationStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all rotations saved in the kv backend.
+func (s *RotationStore) List() ([]*Rotation, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rotations := make([]*Rotation, 0, len(kvPairs))
+	for _, kv := range kvPairs {
+		var r *Rotation
+		if err := json.Unmarshal(kv.Value, &r); err != nil {
+			return nil, err
+		}
+		rotations = append(rotations, r)
+	}
+	sort.Slice(rotations, func(i, j int) bool { return rotations[i].Name < rotations[j].Name })
+
+	return rotations, nil
+}
+
+// Get a rotation by its name, nil if there is none.
+func (s *RotationStore) Get(name string) (*Rotation, error) {
+	kv, err := s.kv.Get(s.storeKeyPrefix + "/" + name)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var r *Rotation
+	err = json.Unmarshal(kv.Value, &r)
+	return r, err
+}
+
+// Put a rotation into the kv backend.
+func (s *RotationStore) Put(r *Rotation) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.storeKeyPrefix+"/"+r.Name, b, nil)
+}
+
+// Remove a rotation from the kv backend.
+func (s *RotationStore) Remove(name string) error {
+	err := s.kv.Delete(s.storeKeyPrefix + "/" + name)
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// onCallChat returns the private chat of the rotation's current on-call user.
+func (b *Bot) onCallChat(name string) (*telebot.Chat, error) {
+	if b.rotations == nil {
+		return nil, nil
+	}
+	r, err := b.rotations.Get(name)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	return &telebot.Chat{ID: int64(r.OnCall(time.Now())), Type: telebot.ChatPrivate}, nil
+}
+
+func (b *Bot) handleOnCall(message *telebot.Message) error {
+	if b.rotations == nil {
+		_, err := b.telegram.Send(message.Chat, responseRotationsNone)
+		return err
+	}
+
+	var rotations []*Rotation
+	if name := strings.TrimSpace(message.Payload); name != "" {
+		r, err := b.rotations.Get(name)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get rotation", "rotation", name, "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't read the rotation.")
+			return err
+		}
+		if r == nil {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseRotationUnkn, name))
+			return err
+		}
+		rotations = []*Rotation{r}
+	} else {
+		var err error
+		rotations, err = b.rotations.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list rotations", "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't list the rotations.")
+			return err
+		}
+	}
+
+	if len(rotations) == 0 {
+		_, err := b.telegram.Send(message.Chat, responseRotationsNone)
+		return err
+	}
+
+	now := time.Now()
+	out := ""
+	for _, r := range rotations {
+		until := r.Until(now)
+		out = out + fmt.Sprintf("%s: %s until %s, then %s\n",
+			r.Name,
+			b.chatName(int64(r.OnCall(now))),
+			until.UTC().Format(onCallTimeFormat),
+			b.chatName(int64(r.OnCall(until))),
+		)
+	}
+
+	_, err := b.telegram.Send(message.Chat, out)
+	return err
+}
+
+func (b *Bot) handleOverride(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) < 2 || len(args) > 3 {
+		_, err := b.telegram.Send(message.Chat, responseOverrideUsage)
+		return err
+	}
+
+	user, err := strconv.Atoi(args[1])
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, responseOverrideUsage)
+		return err
+	}
+
+	var d time.Duration
+	if len(args) == 3 {
+		md, err := model.ParseDuration(args[2])
+		if err != nil || md <= 0 {
+			_, err = b.telegram.Send(message.Chat, responseOverrideUsage)
+			return err
+		}
+		d = time.Duration(md)
+	}
+
+	if b.rotations == nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseRotationUnkn, args[0]))
+		return err
+	}
+	r, err := b.rotations.Get(args[0])
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get rotation", "rotation", args[0], "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't read the rotation.")
+		return err
+	}
+	if r == nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseRotationUnkn, args[0]))
+		return err
+	}
+
+	now := time.Now()
+	o := Override{User: user, From: now, Until: now.Add(d)}
+	if d == 0 {
+		_, _, o.Until = r.shift(now)
+	}
+
+	// Expired overrides aren't needed anymore.
+	overrides := r.Overrides[:0]
+	for _, existing := range r.Overrides {
+		if existing.Until.After(now) {
+			overrides = append(overrides, existing)
+		}
+	}
+	r.Overrides = append(overrides, o)
+
+	if err := b.rotations.Put(r); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to store rotation", "rotation", r.Name, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't store the override.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "on-call overridden",
+		"rotation", r.Name,
+		"user_id", user,
+		"overridden_by", message.Sender.ID,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseOverridden,
+		b.chatName(int64(user)), r.Name, o.Until.UTC().Format(onCallTimeFormat),
+	))
+	return err
+}
+
+func (b *Bot) handleRotation(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) == 0 {
+		return b.sendRotations(message.Chat)
+	}
+
+	if b.rotations == nil {
+		_, err := b.telegram.Send(message.Chat, "I can't store rotations.")
+		return err
+	}
+
+	if args[0] == "delete" && len(args) == 2 {
+		if err := b.rotations.Remove(args[1]); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to remove rotation", "rotation", args[1], "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't delete the rotation.")
+			return err
+		}
+
+		level.Info(b.logger).Log(
+			"msg", "rotation deleted",
+			"rotation", args[1],
+			"deleted_by", message.Sender.ID,
+		)
+
+		_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseRotationGone, args[1]))
+		return err
+	}
+
+	if len(args) < 4 || strings.Contains(args[0], "/") {
+		_, err := b.telegram.Send(message.Chat, responseRotationUsage)
+		return err
+	}
+	length, err := model.ParseDuration(args[1])
+	if err != nil || length <= 0 {
+		_, err = b.telegram.Send(message.Chat, responseRotationUsage)
+		return err
+	}
+	handoff, err := time.Parse(time.RFC3339, args[2])
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, responseRotationUsage)
+		return err
+	}
+
+	r := &Rotation{Name: args[0], Handoff: handoff, Length: time.Duration(length)}
+	for _, arg := range args[3:] {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			_, err = b.telegram.Send(message.Chat, responseRotationUsage)
+			return err
+		}
+		r.Participants = append(r.Participants, id)
+	}
+
+	if err := b.rotations.Put(r); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to store rotation", "rotation", r.Name, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't store the rotation.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "rotation saved",
+		"rotation", r.Name,
+		"participants", len(r.Participants),
+		"saved_by", message.Sender.ID,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseRotationSaved, r.Name, b.chatName(int64(r.OnCall(time.Now())))))
+	return err
+}
+
+// sendRotations sends the definitions of all rotations to the chat.
+func (b *Bot) sendRotations(chat *telebot.Chat) error {
+	var rotations []*Rotation
+	if b.rotations != nil {
+		var err error
+		rotations, err = b.rotations.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list rotations", "err", err)
+			_, err = b.telegram.Send(chat, "I can't list the rotations.")
+			return err
+		}
+	}
+
+	out := ""
+	for _, r := range rotations {
+		participants := make([]string, 0, len(r.Participants))
+		for _, id := range r.Participants {
+			participants = append(participants, strconv.Itoa(id))
+		}
+		out = out + fmt.Sprintf("%s: every %s from %s: %s\n",
+			r.Name, model.Duration(r.Length), r.Handoff.UTC().Format(time.RFC3339), strings.Join(participants, ", "),
+		)
+	}
+
+	_, err := b.telegram.Send(chat, "Rotations:\n"+out+"\n"+responseRotationUsage)
+	return err
+}
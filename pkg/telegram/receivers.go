@@ -0,0 +1,216 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseReceiverUsage   = "Usage: " + CommandReceiver + " [set <receiver>... | unset]"
+	responseReceiverUnknown = "The Alertmanager has no receiver called %s."
+	responseReceiverSet     = "This chat now lists the alerts of %s."
+	responseReceiverUnset   = "This chat now lists the alerts of the receivers sending to it again."
+	responseReceiverBound   = "This chat lists the alerts of %s.\nChange it with " + CommandReceiver + " set <receiver>."
+	responseReceiverConfig  = "This chat lists the alerts of %s, as its webhook is configured there.\nChange it with " + CommandReceiver + " set <receiver>."
+	responseReceiverNone    = "This chat isn't bound to any receiver.\nBind it with " + CommandReceiver + " set <receiver>."
+)
+
+// ReceiverStore writes the receivers chats are bound to into a libkv store backend.
+type ReceiverStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewReceiverStore stores the receivers of chats in the provided kv backend.
+func NewReceiverStore(kv store.Store, storeKeyPrefix string) (*ReceiverStore, error) {
+	return &ReceiverStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get the receivers a chat is bound to, nil if there are none.
+func (s *ReceiverStore) Get(id int64) ([]string, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, id))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var receivers []string
+	err = json.Unmarshal(kv.Value, &receivers)
+	return receivers, err
+}
+
+// Put binds a chat to the receivers.
+func (s *ReceiverStore) Put(id int64, receivers []string) error {
+	b, err := json.Marshal(receivers)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, id), b, nil)
+}
+
+// Remove the receivers a chat is bound to.
+func (s *ReceiverStore) Remove(id int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, id))
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// chatReceivers returns the receivers whose alerts a chat lists.
+// Receivers bound with /receiver take precedence over the ones sending to the chat's webhook.
+func (b *Bot) chatReceivers(ctx context.Context, id int64) (receivers []string, bound bool, err error) {
+	if b.receivers != nil {
+		receivers, err := b.receivers.Get(id)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(receivers) > 0 {
+			return receivers, true, nil
+		}
+	}
+
+	m, err := b.receiverMap(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return b.discoveredReceivers(m, id), false, nil
+}
+
+// receiverChat is a subscribed chat and the receivers whose alerts it lists.
+type receiverChat struct {
+	chat      *telebot.Chat
+	receivers []string
+}
+
+// receiverChats returns the receivers of all subscribed chats like chatReceivers,
+// asking the Alertmanager for its config only once.
+func (b *Bot) receiverChats(ctx context.Context) ([]receiverChat, error) {
+	chats, err := b.chats.List()
+	if err != nil {
+		return nil, err
+	}
+	m, err := b.receiverMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rcs := make([]receiverChat, 0, len(chats))
+	for _, chat := range chats {
+		var receivers []string
+		if b.receivers != nil {
+			receivers, err = b.receivers.Get(chat.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(receivers) == 0 {
+			receivers = b.discoveredReceivers(m, chat.ID)
+		}
+		rcs = append(rcs, receiverChat{chat: chat, receivers: receivers})
+	}
+	return rcs, nil
+}
+
+// receiversRegexp matches any of the receivers' names exactly.
+func receiversRegexp(receivers []string) string {
+	quoted := make([]string, 0, len(receivers))
+	for _, r := range receivers {
+		quoted = append(quoted, regexp.QuoteMeta(r))
+	}
+	return strings.Join(quoted, "|")
+}
+
+func (b *Bot) handleReceiver(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) == 0 {
+		receivers, bound, err := b.chatReceivers(context.TODO(), message.Chat.ID)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get receivers of chat", "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't find the receivers of this chat.")
+			return err
+		}
+		response := responseReceiverNone
+		switch {
+		case bound:
+			response = fmt.Sprintf(responseReceiverBound, strings.Join(receivers, ", "))
+		case len(receivers) > 0:
+			response = fmt.Sprintf(responseReceiverConfig, strings.Join(receivers, ", "))
+		}
+		_, err = b.telegram.Send(message.Chat, response)
+		return err
+	}
+
+	if b.receivers == nil {
+		_, err := b.telegram.Send(message.Chat, "Binding chats to receivers isn't possible without a store.")
+		return err
+	}
+
+	switch {
+	case args[0] == "set" && len(args) > 1:
+		return b.setReceivers(message, args[1:])
+	case args[0] == "unset" && len(args) == 1:
+		if err := b.receivers.Remove(message.Chat.ID); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to unbind chat from receivers", "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't unbind this chat from its receivers.")
+			return err
+		}
+
+		level.Info(b.logger).Log(
+			"msg", "chat unbound from receivers",
+			"chat_id", message.Chat.ID,
+			"user_id", message.Sender.ID,
+		)
+
+		_, err := b.telegram.Send(message.Chat, responseReceiverUnset)
+		return err
+	default:
+		_, err := b.telegram.Send(message.Chat, responseReceiverUsage)
+		return err
+	}
+}
+
+func (b *Bot) setReceivers(message *telebot.Message, receivers []string) error {
+	m, err := b.receiverMap(context.TODO())
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to discover receivers", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the Alertmanager's receivers.")
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, name := range m.Names {
+		known[name] = true
+	}
+	for _, r := range receivers {
+		if !known[r] {
+			_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseReceiverUnknown, r))
+			return err
+		}
+	}
+
+	if err := b.receivers.Put(message.Chat.ID, receivers); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to bind chat to receivers", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't bind this chat to the receivers.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "chat bound to receivers",
+		"receivers", strings.Join(receivers, ","),
+		"chat_id", message.Chat.ID,
+		"user_id", message.Sender.ID,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseReceiverSet, strings.Join(receivers, ", ")))
+	return err
+}
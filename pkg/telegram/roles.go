@@ -0,0 +1,253 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// Role of a user determines the commands the user is allowed to use.
+// Every role is allowed to use the commands of the roles below it.
+type Role int
+
+const (
+	// RoleNone is the role of everyone not known to the bot.
+	RoleNone Role = iota
+	// RoleViewer can read alerts, silences and the status.
+	RoleViewer
+	// RoleOperator can additionally make changes to the Alertmanager, like silencing alerts.
+	RoleOperator
+	// RoleAdmin can additionally manage chats and users' roles.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleNone:     "none",
+	RoleViewer:   "viewer",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+// ParseRole returns the Role for its name.
+func ParseRole(name string) (Role, error) {
+	for r, n := range roleNames {
+		if n == name && r != RoleNone {
+			return r, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", name)
+}
+
+const (
+	responseGrantUsage = "Usage: " + CommandGrant + " <user-id> <viewer|operator|admin>"
+	responseGranted    = "User %d is now %s."
+	responseRevoked    = "User %d has no role anymore."
+	responseRevokeCLI  = "User %d is an admin given on the command line, I can't revoke that."
+	responseForbidden  = "Sorry, you're not allowed to use this command."
+)
+
+// RoleStore writes the roles of users or chats to a libkv store backend.
+type RoleStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewRoleStore stores roles in the provided kv backend.
+func NewRoleStore(kv store.Store, storeKeyPrefix string) (*RoleStore, error) {
+	return &RoleStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all roles saved in the kv backend by ID.
+func (s *RoleStore) List() (map[int64]Role, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return map[int64]Role{}, nil
+		}
+		return nil, err
+	}
+
+	roles := make(map[int64]Role, len(kvPairs))
+	for _, kv := range kvPairs {
+		id, err := strconv.ParseInt(path.Base(kv.Key), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		r, err := ParseRole(string(kv.Value))
+		if err != nil {
+			return nil, err
+		}
+		roles[id] = r
+	}
+
+	return roles, nil
+}
+
+// Get the role for an ID, RoleNone if there is none.
+func (s *RoleStore) Get(id int64) (Role, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, id))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return RoleNone, nil
+		}
+		return RoleNone, err
+	}
+	return ParseRole(string(kv.Value))
+}
+
+// Set the role for an ID.
+func (s *RoleStore) Set(id int64, r Role) error {
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, id), []byte(r.String()), nil)
+}
+
+// Remove the role for an ID.
+func (s *RoleStore) Remove(id int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, id))
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// role returns the role of a user sending commands to a chat.
+// Admins given on the command line always have RoleAdmin,
+// members of trusted chats have at least the chat's role.
+func (b *Bot) role(chat *telebot.Chat, user *telebot.User) Role {
+	if b.isAdminID(user.ID) {
+		return RoleAdmin
+	}
+
+	r := RoleNone
+	if b.roles != nil {
+		var err error
+		r, err = b.roles.Get(int64(user.ID))
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get role of user", "user_id", user.ID, "err", err)
+			r = RoleNone
+		}
+	}
+
+	if cr := b.chatRole(chat, user); cr > r {
+		r = cr
+	}
+	return r
+}
+
+func (b *Bot) handleGrant(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) == 0 {
+		return b.sendRoles(message.Chat)
+	}
+	if len(args) != 2 {
+		_, err := b.telegram.Send(message.Chat, responseGrantUsage)
+		return err
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, responseGrantUsage)
+		return err
+	}
+	r, err := ParseRole(args[1])
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, responseGrantUsage)
+		return err
+	}
+
+	if b.roles == nil {
+		_, err = b.telegram.Send(message.Chat, "I can't store roles for users.")
+		return err
+	}
+	if err := b.roles.Set(id, r); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to store role", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't store the role for this user.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "role granted",
+		"user_id", id,
+		"role", r,
+		"granted_by", message.Sender.ID,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseGranted, id, r))
+	return err
+}
+
+func (b *Bot) handleRevoke(message *telebot.Message) error {
+	id, err := strconv.ParseInt(strings.TrimSpace(message.Payload), 10, 64)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, "Usage: "+CommandRevoke+" <user-id>")
+		return err
+	}
+
+	if b.isAdminID(int(id)) {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseRevokeCLI, id))
+		return err
+	}
+
+	if b.roles == nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseRevoked, id))
+		return err
+	}
+	if err := b.roles.Remove(id); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove role", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't remove the role of this user.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "role revoked",
+		"user_id", id,
+		"revoked_by", message.Sender.ID,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(responseRevoked, id))
+	return err
+}
+
+// sendRoles sends all users with a role to the chat.
+func (b *Bot) sendRoles(chat *telebot.Chat) error {
+	roles := map[int64]Role{}
+	if b.roles != nil {
+		var err error
+		roles, err = b.roles.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list roles", "err", err)
+			_, err = b.telegram.Send(chat, "I can't list the users' roles.")
+			return err
+		}
+	}
+	for _, id := range b.admins {
+		roles[int64(id)] = RoleAdmin
+	}
+
+	ids := make([]int64, 0, len(roles))
+	for id := range roles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	list := ""
+	for _, id := range ids {
+		list = list + fmt.Sprintf("%d: %s\n", id, roles[id])
+	}
+
+	_, err := b.telegram.Send(chat, "Users with a role:\n"+list+"\n"+responseGrantUsage)
+	return err
+}
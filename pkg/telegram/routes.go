@@ -0,0 +1,158 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseRoutesTestUsage = "Usage: " + CommandRoutes + " test <label>=<value>...\n" +
+		"For example: " + CommandRoutes + " test alertname=NodeDown severity=critical"
+	responseRoutesTest = "Alerts with %s are sent to:\n%s"
+
+	routesHeader = "<b>Routing tree:</b>\n<pre>"
+	routesFooter = "</pre>"
+	routesSnip   = "[SNIP]\n"
+)
+
+// routingTree loads the Alertmanager's current config and returns its routing tree.
+func (b *Bot) routingTree(ctx context.Context) (*dispatch.Route, error) {
+	status, err := b.alertmanager.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.Config == nil || status.Config.Original == nil || *status.Config.Original == "" {
+		return nil, fmt.Errorf("config is empty")
+	}
+
+	c, err := config.Load(*status.Config.Original)
+	if err != nil {
+		return nil, err
+	}
+
+	return dispatch.NewRoute(c.Route, nil), nil
+}
+
+func (b *Bot) handleRoutes(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) > 0 && args[0] != "test" {
+		_, err := b.telegram.Send(message.Chat, responseRoutesTestUsage)
+		return err
+	}
+
+	route, err := b.routingTree(context.TODO())
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to load routing tree", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to load the routing tree... %v", err))
+		return err
+	}
+
+	if len(args) > 0 {
+		return b.testRoutes(message, route, args[1:])
+	}
+
+	var sb strings.Builder
+	writeRoute(&sb, route, "", true)
+
+	_, err = b.telegram.Send(message.Chat, routesHeader+truncateRoutes(sb.String())+routesFooter, &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+	return err
+}
+
+// truncateRoutes cuts the tree off after the last line that fits into a message with the header and footer,
+// so that the HTML stays intact.
+func truncateRoutes(tree string) string {
+	max := 4095 - len(routesHeader) - len(routesFooter) - len(routesSnip)
+	if len(tree) <= max {
+		return tree
+	}
+	return tree[:strings.LastIndex(tree[:max], "\n")+1] + routesSnip
+}
+
+// writeRoute writes the route and its children as a tree like `amtool config routes` does.
+// The root is called default-route, other routes without matchers are only shown by their receiver.
+func writeRoute(sb *strings.Builder, r *dispatch.Route, indent string, last bool) {
+	branch, child := "├── ", "│   "
+	if last {
+		branch, child = "└── ", "    "
+	}
+
+	name := r.Matchers.String() + "  "
+	switch {
+	case indent == "":
+		name = "default-route  "
+	case len(r.Matchers) == 0:
+		name = ""
+	}
+
+	fmt.Fprintf(sb, "%s%s%sreceiver: %s", indent, branch, html.EscapeString(name), html.EscapeString(r.RouteOpts.Receiver))
+	if r.Continue {
+		sb.WriteString("  continue: true")
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(sb, "%s%s  group_by: %s  wait: %s  interval: %s  repeat: %s\n",
+		indent, child,
+		html.EscapeString(groupBy(r.RouteOpts)),
+		model.Duration(r.RouteOpts.GroupWait),
+		model.Duration(r.RouteOpts.GroupInterval),
+		model.Duration(r.RouteOpts.RepeatInterval),
+	)
+
+	for i, cr := range r.Routes {
+		writeRoute(sb, cr, indent+child, i == len(r.Routes)-1)
+	}
+}
+
+// groupBy returns the labels a route groups alerts by.
+func groupBy(opts dispatch.RouteOpts) string {
+	if opts.GroupByAll {
+		return "[...]"
+	}
+	names := make([]string, 0, len(opts.GroupBy))
+	for name := range opts.GroupBy {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+// testRoutes shows the routes and receivers alerts with the given labels would be sent to.
+func (b *Bot) testRoutes(message *telebot.Message, route *dispatch.Route, args []string) error {
+	if len(args) == 0 {
+		_, err := b.telegram.Send(message.Chat, responseRoutesTestUsage)
+		return err
+	}
+
+	ls := model.LabelSet{}
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			_, err := b.telegram.Send(message.Chat, responseRoutesTestUsage)
+			return err
+		}
+		name, value := model.LabelName(arg[:i]), model.LabelValue(strings.Trim(arg[i+1:], `"`))
+		if !name.IsValid() {
+			_, err := b.telegram.Send(message.Chat, fmt.Sprintf("%s isn't a valid label name.", name))
+			return err
+		}
+		ls[name] = value
+	}
+
+	var lines []string
+	for _, r := range route.Match(ls) {
+		lines = append(lines, fmt.Sprintf("%s via %s", r.RouteOpts.Receiver, r.Key()))
+	}
+
+	_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseRoutesTest, ls, strings.Join(lines, "\n")))
+	return err
+}
@@ -0,0 +1,196 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseSilenceUsage = "Usage: " + CommandSilence + " <duration> <matchers...> [comment]\n" +
+		"For example: " + CommandSilence + " 2h alertname=NodeDown instance=~node-1.* Replacing the disks"
+	responseSilencePreview   = "🔕 Silence <code>%s</code> for %s?\n"
+	responseSilenceNone      = "It doesn't silence any alert right now."
+	responseSilenceBroad     = "⚠️ These matchers are broad, they silence %d alerts!\n"
+	responseSilenceCreated   = "🔕 %s silenced <code>%s</code> for %s with the silence <code>%s</code>."
+	responseSilenceCancelled = "Alright, I won't silence <code>%s</code>."
+	responseSilenceOutdated  = "This silence is outdated, please use " + CommandSilence + " again."
+
+	// silencePreviewAlerts is the number of affected alerts listed in a preview.
+	silencePreviewAlerts = 10
+)
+
+// silenceConfirmButton is the inline button to confirm or cancel a previewed silence.
+var silenceConfirmButton = telebot.InlineButton{Unique: "silence_confirm"}
+
+// silencePreview is a silence waiting for confirmation.
+type silencePreview struct {
+	silence *types.Silence
+	chat    int64
+}
+
+// parseSilence parses the arguments of /silence into a silence starting now.
+// All arguments after the matchers are the silence's comment.
+func parseSilence(payload string, now time.Time) (*types.Silence, error) {
+	args := strings.Fields(payload)
+	if len(args) < 2 {
+		return nil, fmt.Errorf("a duration and matchers are required")
+	}
+
+	d, err := model.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%s isn't a valid duration", args[0])
+	}
+
+	s := &types.Silence{
+		StartsAt: now,
+		EndsAt:   now.Add(time.Duration(d)),
+		Comment:  "Silenced with Telegram",
+	}
+
+	i := 1
+	for ; i < len(args); i++ {
+		m, err := labels.ParseMatcher(args[i])
+		if err != nil {
+			if i == 1 {
+				return nil, err
+			}
+			break
+		}
+		s.Matchers = append(s.Matchers, m)
+	}
+	if i < len(args) {
+		s.Comment = strings.Join(args[i:], " ")
+	}
+	sort.Sort(s.Matchers)
+
+	return s, nil
+}
+
+func (b *Bot) handleSilence(message *telebot.Message) error {
+	s, err := parseSilence(message.Payload, time.Now())
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%v\n%s", err, responseSilenceUsage))
+		return err
+	}
+	s.CreatedBy = displayName(message.Sender)
+
+	alerts, err := b.alertmanager.ListAlerts(context.TODO(), alertmanager.AlertFilter{
+		Active:      true,
+		Silenced:    true,
+		Inhibited:   true,
+		Unprocessed: true,
+	})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alerts... %v", err))
+		return err
+	}
+
+	var affected []*alertmanager.Alert
+	for _, a := range alerts {
+		if s.Matchers.Matches(a.Labels) {
+			affected = append(affected, a)
+		}
+	}
+
+	token := b.pageQueries.add(silencePreview{silence: s, chat: message.Chat.ID})
+
+	yes, no := silenceConfirmButton, silenceConfirmButton
+	yes.Text, yes.Data = "✅ Yes", token+" yes"
+	no.Text, no.Data = "❌ No", token+" no"
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(silencePreviewMessage(s, affected, b.silenceWarnAt)), &telebot.SendOptions{
+		ParseMode:   telebot.ModeHTML,
+		ReplyMarkup: &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{yes, no}}},
+	})
+	return err
+}
+
+// silencePreviewMessage lists the alerts the silence would silence,
+// with a warning if there are more than threshold.
+func silencePreviewMessage(s *types.Silence, affected []*alertmanager.Alert, threshold int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, responseSilencePreview, html.EscapeString(s.Matchers.String()), model.Duration(s.EndsAt.Sub(s.StartsAt)))
+	if threshold > 0 && len(affected) > threshold {
+		fmt.Fprintf(&sb, responseSilenceBroad, len(affected))
+	}
+
+	switch len(affected) {
+	case 0:
+		sb.WriteString(responseSilenceNone + "\n")
+	case 1:
+		sb.WriteString("It silences 1 alert:\n")
+	default:
+		fmt.Fprintf(&sb, "It silences %d alerts:\n", len(affected))
+	}
+	for i, a := range affected {
+		if i == silencePreviewAlerts {
+			fmt.Fprintf(&sb, "and %d more\n", len(affected)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "    %s\n", alertLine(a))
+	}
+
+	return sb.String()
+}
+
+func (b *Bot) handleSilenceConfirmButton(c *telebot.Callback) error {
+	args := strings.Fields(c.Data)
+	if len(args) != 2 {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseSilenceOutdated})
+	}
+	p, _ := b.pageQueries.get(args[0])
+	preview, ok := p.(silencePreview)
+	if !ok || c.Message == nil || c.Message.Chat == nil || preview.chat != c.Message.Chat.ID {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseSilenceOutdated})
+	}
+
+	// Answering removes the preview, so that the silence isn't created twice.
+	b.pageQueries.remove(args[0])
+
+	s := *preview.silence
+	matchers := html.EscapeString(s.Matchers.String())
+
+	if args[1] != "yes" {
+		if _, err := b.telegram.Edit(c.Message, fmt.Sprintf(responseSilenceCancelled, matchers), &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+			return err
+		}
+		return b.telegram.Respond(c)
+	}
+
+	// The silence starts once it's confirmed.
+	d := s.EndsAt.Sub(s.StartsAt)
+	s.StartsAt = time.Now()
+	s.EndsAt = s.StartsAt.Add(d)
+
+	id, err := b.createSilence(context.TODO(), &s, c.Message.Chat, c.Sender)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to create silence", "err", err)
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't create the silence."})
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "silence created",
+		"silence_id", id,
+		"matchers", s.Matchers.String(),
+		"duration", model.Duration(d),
+		"user_id", c.Sender.ID,
+	)
+
+	out := fmt.Sprintf(responseSilenceCreated, html.EscapeString(displayName(c.Sender)), matchers, model.Duration(d), html.EscapeString(id))
+	if _, err := b.telegram.Edit(c.Message, out, &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+		return err
+	}
+	return b.telegram.Respond(c)
+}
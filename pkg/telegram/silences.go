@@ -0,0 +1,311 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-openapi/strfmt"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseSilencesUsage = "Usage: " + CommandSilences + " [active] [pending] [expired] [by:<creator>] [matchers...]\n" +
+		"For example: " + CommandSilences + " expired by:elliot alertname=NodeDown"
+	responseSilencesNone     = "No silences right now."
+	responseSilencesOutdated = "This list is outdated, please ask for " + CommandSilences + " again."
+	responseExtendUsage      = "Usage: " + CommandExtend + " <silence-id> <duration>\n" +
+		"For example: " + CommandExtend + " 34f5f82b-b66f-456b-aff7-b556a7eafe81 2h"
+	responseExtendNotFound = "There is no silence with ID %s."
+	responseExtendExpired  = "The silence %s has expired already."
+	responseExtended       = "⏳ %s extended the silence %s by %s, it now ends at %s."
+
+	silencesPageSize  = 10
+	silencesPageBytes = 3800
+	// silenceExtension is how long the Extend button extends a silence.
+	silenceExtension = time.Hour
+)
+
+var (
+	// silencesPageButton is the inline button to show another page of silences.
+	silencesPageButton = telebot.InlineButton{Unique: "silences"}
+	// extendButton is the inline button to extend a silence by its ID.
+	extendButton = telebot.InlineButton{Unique: "extend"}
+)
+
+// silenceQuery is what the user asked for with /silences.
+type silenceQuery struct {
+	filter alertmanager.SilenceFilter
+	chat   int64
+}
+
+// parseSilenceQuery parses the arguments of /silences.
+// Without any states, active and pending silences are listed.
+func parseSilenceQuery(payload string) (silenceQuery, error) {
+	var q silenceQuery
+	for _, arg := range strings.Fields(payload) {
+		switch {
+		case arg == string(types.SilenceStateActive), arg == string(types.SilenceStatePending), arg == string(types.SilenceStateExpired):
+			q.filter.States = append(q.filter.States, types.SilenceState(arg))
+		case strings.HasPrefix(arg, "by:"):
+			q.filter.CreatedBy = strings.TrimPrefix(arg, "by:")
+		default:
+			if _, err := labels.ParseMatcher(arg); err != nil {
+				return q, err
+			}
+			q.filter.Matchers = append(q.filter.Matchers, arg)
+		}
+	}
+
+	if len(q.filter.States) == 0 {
+		q.filter.States = []types.SilenceState{types.SilenceStateActive, types.SilenceStatePending}
+	}
+
+	return q, nil
+}
+
+func (b *Bot) handleSilences(message *telebot.Message) error {
+	query, err := parseSilenceQuery(message.Payload)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%v\n%s", err, responseSilencesUsage))
+		return err
+	}
+	query.chat = message.Chat.ID
+
+	token := b.pageQueries.add(query)
+	out, opts, err := b.renderSilences(token, query, 0)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list silences", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list silences... %v", err))
+		return err
+	}
+
+	_, err = b.telegram.Send(message.Chat, out, opts)
+	return err
+}
+
+// silencePages splits the silences into pages fitting into a single message.
+func silencePages(silences []*types.Silence) [][]*types.Silence {
+	var (
+		pages [][]*types.Silence
+		page  []*types.Silence
+		size  int
+	)
+	for _, s := range silences {
+		n := len(alertmanager.SilenceMessage(s)) + 1
+
+		if len(page) > 0 && (len(page) == silencesPageSize || size+n > silencesPageBytes) {
+			pages = append(pages, page)
+			page, size = nil, 0
+		}
+		page = append(page, s)
+		size += n
+	}
+	if len(page) > 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// renderSilences lists the silences for the query and renders the requested page with its buttons.
+// Silences ending last are listed first.
+func (b *Bot) renderSilences(token string, query silenceQuery, page int) (string, *telebot.SendOptions, error) {
+	silences, err := b.alertmanager.ListSilences(context.TODO(), query.filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := &telebot.SendOptions{ParseMode: telebot.ModeHTML}
+	if len(silences) == 0 {
+		return responseSilencesNone, opts, nil
+	}
+
+	sort.SliceStable(silences, func(i, j int) bool {
+		return silences[i].EndsAt.After(silences[j].EndsAt)
+	})
+
+	pages := silencePages(silences)
+	if page < 0 {
+		page = 0
+	}
+	if page >= len(pages) {
+		page = len(pages) - 1
+	}
+
+	var (
+		messages = make([]string, 0, len(pages[page]))
+		keyboard [][]telebot.InlineButton
+	)
+	for _, s := range pages[page] {
+		messages = append(messages, alertmanager.SilenceMessage(s))
+		if !alertmanager.Resolved(s) {
+			keyboard = append(keyboard, []telebot.InlineButton{extendSilenceButton(s, silenceExtension)})
+		}
+	}
+
+	out := strings.Join(messages, "\n")
+	if len(pages) > 1 {
+		out = strings.TrimSpace(out) + fmt.Sprintf("\n\n<i>Page %d of %d, %d silences</i>", page+1, len(pages), len(silences))
+		keyboard = append(keyboard, pageButtons(silencesPageButton, token, page, len(pages)).InlineKeyboard...)
+	}
+	if len(keyboard) > 0 {
+		opts.ReplyMarkup = &telebot.ReplyMarkup{InlineKeyboard: keyboard}
+	}
+
+	return b.truncateMessage(out), opts, nil
+}
+
+// extendSilenceButton returns the button to extend the silence by d.
+func extendSilenceButton(s *types.Silence, d time.Duration) telebot.InlineButton {
+	button := extendButton
+	button.Text = fmt.Sprintf("⏳ Extend %s by %s", silenceName(s), model.Duration(d))
+	button.Data = s.ID + " " + model.Duration(d).String()
+	return button
+}
+
+// shortSilenceID is the first part of a silence's ID, like the Alertmanager's UI shows it.
+func shortSilenceID(id string) string {
+	if i := strings.Index(id, "-"); i > 0 {
+		return id[:i]
+	}
+	return id
+}
+
+func (b *Bot) handleSilencesPageButton(c *telebot.Callback) error {
+	args := strings.Fields(c.Data)
+	if len(args) != 2 {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseSilencesOutdated})
+	}
+	q, _ := b.pageQueries.get(args[0])
+	query, ok := q.(silenceQuery)
+	if !ok || c.Message == nil || c.Message.Chat == nil || query.chat != c.Message.Chat.ID {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseSilencesOutdated})
+	}
+	page, err := strconv.Atoi(args[1])
+	if err != nil {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: responseSilencesOutdated})
+	}
+
+	out, opts, err := b.renderSilences(args[0], query, page)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list silences", "err", err)
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't list the silences."})
+	}
+
+	if _, err := b.telegram.Edit(c.Message, out, opts); err != nil {
+		return err
+	}
+	return b.telegram.Respond(c)
+}
+
+// extendSilence re-posts the silence with the same ID and matchers ending d later.
+// The Alertmanager updates the silence in place as long as it hasn't expired,
+// otherwise the silence with the new ID is watched instead.
+func (b *Bot) extendSilence(ctx context.Context, s *types.Silence, d time.Duration) (string, error) {
+	b.silencesMu.Lock()
+	defer b.silencesMu.Unlock()
+
+	extended := *s
+	extended.EndsAt = s.EndsAt.Add(d)
+	id, err := b.alertmanager.CreateSilence(ctx, &extended)
+	if err != nil {
+		return "", err
+	}
+
+	if id != s.ID {
+		if err := b.rewatchSilence(s.ID, id, extended.EndsAt); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to watch extended silence", "silence_id", id, "err", err)
+		}
+	}
+
+	return id, nil
+}
+
+func (b *Bot) handleExtend(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) != 2 || !strfmt.IsUUID(args[0]) {
+		_, err := b.telegram.Send(message.Chat, responseExtendUsage)
+		return err
+	}
+	d, err := model.ParseDuration(args[1])
+	if err != nil || d <= 0 {
+		_, err := b.telegram.Send(message.Chat, responseExtendUsage)
+		return err
+	}
+
+	response, err := b.extend(args[0], time.Duration(d), message.Sender)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to extend the silence... %v", err))
+		return err
+	}
+
+	_, err = b.telegram.Send(message.Chat, response, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+func (b *Bot) handleExtendButton(c *telebot.Callback) error {
+	args := strings.Fields(c.Data)
+	if len(args) != 2 || !strfmt.IsUUID(args[0]) {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I don't understand this button."})
+	}
+	d, err := model.ParseDuration(args[1])
+	if err != nil || d <= 0 {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I don't understand this button."})
+	}
+
+	response, err := b.extend(args[0], time.Duration(d), c.Sender)
+	if err != nil {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't extend the silence."})
+	}
+
+	if err := b.telegram.Respond(c); err != nil {
+		return err
+	}
+	_, err = b.telegram.Send(c.Message.Chat, response, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// extend extends the silence with the ID and returns the response for the user.
+// Silences that don't exist or expired already are responded to without an error.
+func (b *Bot) extend(id string, d time.Duration, user *telebot.User) (string, error) {
+	s, err := b.alertmanager.GetSilence(context.TODO(), id)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get silence", "err", err)
+		return "", err
+	}
+	if s == nil {
+		return fmt.Sprintf(responseExtendNotFound, html.EscapeString(id)), nil
+	}
+	if alertmanager.Resolved(s) || s.Status.State == types.SilenceStateExpired {
+		return fmt.Sprintf(responseExtendExpired, html.EscapeString(id)), nil
+	}
+
+	newID, err := b.extendSilence(context.TODO(), s, d)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to extend silence", "err", err)
+		return "", err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "silence extended",
+		"silence_id", newID,
+		"duration", model.Duration(d),
+		"user_id", user.ID,
+	)
+
+	return fmt.Sprintf(responseExtended,
+		html.EscapeString(displayName(user)),
+		"<code>"+html.EscapeString(newID)+"</code>",
+		model.Duration(d),
+		s.EndsAt.Add(d).UTC().Format(onCallTimeFormat),
+	), nil
+}
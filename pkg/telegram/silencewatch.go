@@ -0,0 +1,347 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseSilenceReminder = "⏰ The silence of <b>%s</b> ends in %s.\n\n%s"
+	responseSilenceAppeared = "🔕 A new silence of <b>%s</b> was created outside of this bot.\n\n%s"
+	responseSilenceVanished = "🔔 The silence <code>%s</code> of <b>%s</b> created outside of this bot isn't active anymore."
+	responseSilenceLapse    = "💤 <b>Left to lapse by</b> %s"
+)
+
+// lapseButton is the inline button to let a silence expire as planned.
+var lapseButton = telebot.InlineButton{Unique: "lapse", Text: "💤 Let it lapse"}
+
+// WatchedSilence is an active or pending silence the bot reminds of before it expires.
+type WatchedSilence struct {
+	ID     string    `json:"id"`
+	EndsAt time.Time `json:"endsAt"`
+	// ChatID is the chat the silence was created in with the bot, 0 if it was created outside of the bot.
+	ChatID int64 `json:"chatId,omitempty"`
+	// UserID is the user who created the silence with the bot.
+	UserID int `json:"userId,omitempty"`
+	// Receivers are the receivers a silence created outside of the bot is routed to.
+	Receivers  []string  `json:"receivers,omitempty"`
+	RemindedAt time.Time `json:"remindedAt,omitempty"`
+}
+
+// SilenceStore writes watched silences to a libkv store backend.
+type SilenceStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewSilenceStore stores watched silences in the provided kv backend.
+func NewSilenceStore(kv store.Store, storeKeyPrefix string) (*SilenceStore, error) {
+	return &SilenceStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all watched silences saved in the kv backend.
+func (s *SilenceStore) List() ([]*WatchedSilence, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	silences := make([]*WatchedSilence, 0, len(kvPairs))
+	for _, kv := range kvPairs {
+		var ws *WatchedSilence
+		if err := json.Unmarshal(kv.Value, &ws); err != nil {
+			return nil, err
+		}
+		silences = append(silences, ws)
+	}
+
+	return silences, nil
+}
+
+// Put a watched silence into the kv backend.
+func (s *SilenceStore) Put(ws *WatchedSilence) error {
+	b, err := json.Marshal(ws)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%s", s.storeKeyPrefix, ws.ID), b, nil)
+}
+
+// Remove a watched silence by its ID.
+func (s *SilenceStore) Remove(id string) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%s", s.storeKeyPrefix, id))
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// createSilence creates the silence and watches it as created in the chat by the user.
+func (b *Bot) createSilence(ctx context.Context, s *types.Silence, chat *telebot.Chat, user *telebot.User) (string, error) {
+	b.silencesMu.Lock()
+	defer b.silencesMu.Unlock()
+
+	id, err := b.alertmanager.CreateSilence(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	if b.silences != nil && b.silenceReminder > 0 {
+		ws := &WatchedSilence{ID: id, EndsAt: s.EndsAt, ChatID: chat.ID, UserID: user.ID}
+		if err := b.silences.Put(ws); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to watch silence", "silence_id", id, "err", err)
+		}
+	}
+
+	return id, nil
+}
+
+// rewatchSilence watches the silence with the new ID instead of the old one, if the old one was watched.
+// The caller must hold silencesMu.
+func (b *Bot) rewatchSilence(oldID, newID string, endsAt time.Time) error {
+	if b.silences == nil {
+		return nil
+	}
+
+	watched, err := b.silences.List()
+	if err != nil {
+		return err
+	}
+	for _, ws := range watched {
+		if ws.ID != oldID {
+			continue
+		}
+		if err := b.silences.Remove(oldID); err != nil {
+			return err
+		}
+		ws.ID, ws.EndsAt, ws.RemindedAt = newID, endsAt, time.Time{}
+		return b.silences.Put(ws)
+	}
+	return nil
+}
+
+// watchSilences reminds of silences about to expire and notifies the chats of the receivers
+// about silences created outside of the bot appearing and vanishing.
+func (b *Bot) watchSilences() error {
+	// The chats' receivers are looked up before locking, so that a slow Alertmanager doesn't block /extend.
+	chats, err := b.receiverChats(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	b.silencesMu.Lock()
+	defer b.silencesMu.Unlock()
+
+	silences, err := b.alertmanager.ListSilences(context.TODO(), alertmanager.SilenceFilter{
+		States: []types.SilenceState{types.SilenceStateActive, types.SilenceStatePending},
+	})
+	if err != nil {
+		return err
+	}
+	watched, err := b.silences.List()
+	if err != nil {
+		return err
+	}
+
+	// Without any watched silences yet, the silences already there aren't news.
+	seed := !b.silencesWatched && len(watched) == 0
+	b.silencesWatched = true
+
+	byID := make(map[string]*WatchedSilence, len(watched))
+	for _, ws := range watched {
+		byID[ws.ID] = ws
+	}
+
+	current := make(map[string]bool, len(silences))
+	for _, s := range silences {
+		current[s.ID] = true
+
+		ws, ok := byID[s.ID]
+		if !ok {
+			ws = &WatchedSilence{ID: s.ID, EndsAt: s.EndsAt, Receivers: b.silenceReceivers(s)}
+			if !seed {
+				b.notifySilenceChats(chats, ws, fmt.Sprintf(responseSilenceAppeared, html.EscapeString(silenceName(s)), alertmanager.SilenceMessage(s)), nil)
+				level.Info(b.logger).Log("msg", "silence appeared", "silence_id", s.ID, "created_by", s.CreatedBy)
+				b.auditAction(0, "silence-appeared", s.ID)
+			}
+		}
+		if !ws.EndsAt.Equal(s.EndsAt) {
+			// The silence has been extended and is reminded of again.
+			ws.EndsAt = s.EndsAt
+			ws.RemindedAt = time.Time{}
+		}
+
+		if ws.RemindedAt.IsZero() && time.Until(s.EndsAt) <= b.silenceReminder {
+			b.notifySilenceChats(chats, ws, silenceReminder(s), &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{
+				{extendSilenceButton(s, silenceExtension), lapseSilenceButton(s)},
+			}})
+			ws.RemindedAt = time.Now()
+			level.Info(b.logger).Log("msg", "reminded of expiring silence", "silence_id", s.ID, "chat_id", ws.ChatID)
+			b.auditAction(ws.ChatID, "silence-reminder", s.ID)
+		}
+
+		if err := b.silences.Put(ws); err != nil {
+			return err
+		}
+	}
+
+	for _, ws := range watched {
+		if current[ws.ID] {
+			continue
+		}
+		if ws.ChatID == 0 {
+			b.notifySilenceChats(chats, ws, fmt.Sprintf(responseSilenceVanished, html.EscapeString(ws.ID), html.EscapeString(b.vanishedSilenceName(ws.ID))), nil)
+			level.Info(b.logger).Log("msg", "silence vanished", "silence_id", ws.ID)
+		}
+		if err := b.silences.Remove(ws.ID); err != nil {
+			return err
+		}
+		b.auditAction(ws.ChatID, "silence-expired", ws.ID)
+	}
+
+	return nil
+}
+
+// silenceReceivers returns the receivers alerts with the labels a silence matches equally on are routed to.
+func (b *Bot) silenceReceivers(s *types.Silence) []string {
+	route, err := b.routingTree(context.TODO())
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get routing tree", "silence_id", s.ID, "err", err)
+		return nil
+	}
+
+	ls := model.LabelSet{}
+	for _, m := range s.Matchers {
+		if m.Type == labels.MatchEqual {
+			ls[model.LabelName(m.Name)] = model.LabelValue(m.Value)
+		}
+	}
+
+	var receivers []string
+	for _, r := range route.Match(ls) {
+		receivers = appendReceiver(receivers, r.RouteOpts.Receiver)
+	}
+	return receivers
+}
+
+// silenceChats returns the chat the silence was created in and its creator's private chat,
+// or the subscribed chats listing the silence's receivers if it was created outside of the bot.
+// The creator is only sent to privately if they started the bot, otherwise the chat reminds them.
+func (b *Bot) silenceChats(subscribed []receiverChat, ws *WatchedSilence) []*telebot.Chat {
+	if ws.ChatID != 0 {
+		chats := []*telebot.Chat{{ID: ws.ChatID}}
+		if ws.UserID != 0 && int64(ws.UserID) != ws.ChatID {
+			for _, rc := range subscribed {
+				if rc.chat.ID == int64(ws.UserID) {
+					chats = append(chats, rc.chat)
+				}
+			}
+		}
+		return chats
+	}
+
+	var chats []*telebot.Chat
+	for _, rc := range subscribed {
+		if anyReceiver(rc.receivers, ws.Receivers) {
+			chats = append(chats, rc.chat)
+		}
+	}
+	return chats
+}
+
+// anyReceiver returns true if any of the receivers is one of the wanted ones.
+func anyReceiver(receivers, wanted []string) bool {
+	for _, r := range receivers {
+		for _, w := range wanted {
+			if r == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notifySilenceChats sends the message to the chats returned by silenceChats.
+func (b *Bot) notifySilenceChats(subscribed []receiverChat, ws *WatchedSilence, message string, markup *telebot.ReplyMarkup) {
+	for _, chat := range b.silenceChats(subscribed, ws) {
+		_, err := b.telegram.Send(chat, b.truncateMessage(message), &telebot.SendOptions{
+			ParseMode:   telebot.ModeHTML,
+			ReplyMarkup: markup,
+		})
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send silence notification", "chat_id", chat.ID, "silence_id", ws.ID, "err", err)
+		}
+	}
+}
+
+// vanishedSilenceName looks up the alert name of a silence that isn't active anymore.
+func (b *Bot) vanishedSilenceName(id string) string {
+	s, err := b.alertmanager.GetSilence(context.TODO(), id)
+	if err != nil || s == nil {
+		return shortSilenceID(id)
+	}
+	return silenceName(s)
+}
+
+// silenceName returns the alert name a silence matches or its short ID.
+func silenceName(s *types.Silence) string {
+	for _, m := range s.Matchers {
+		if m.Name == model.AlertNameLabel && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return shortSilenceID(s.ID)
+}
+
+func silenceReminder(s *types.Silence) string {
+	return fmt.Sprintf(responseSilenceReminder,
+		html.EscapeString(silenceName(s)),
+		durafmt.Parse(time.Until(s.EndsAt).Round(time.Minute)),
+		alertmanager.SilenceMessage(s),
+	)
+}
+
+func lapseSilenceButton(s *types.Silence) telebot.InlineButton {
+	button := lapseButton
+	button.Data = s.ID
+	return button
+}
+
+func (b *Bot) handleLapseButton(c *telebot.Callback) error {
+	id := strings.TrimSpace(c.Data)
+
+	s, err := b.alertmanager.GetSilence(context.TODO(), id)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get silence", "err", err)
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "I can't find the silence."})
+	}
+	if s == nil {
+		return b.telegram.Respond(c, &telebot.CallbackResponse{Text: "The silence isn't there anymore."})
+	}
+
+	out := strings.TrimSpace(silenceReminder(s)) + "\n\n" + fmt.Sprintf(responseSilenceLapse, html.EscapeString(displayName(c.Sender)))
+	if _, err := b.telegram.Edit(c.Message, out, &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "silence left to lapse", "silence_id", s.ID, "user_id", c.Sender.ID)
+
+	return b.telegram.Respond(c)
+}
@@ -0,0 +1,176 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseTopicsNone    = "There are no topics to subscribe to."
+	responseTopicUnknown  = "There is no topic called %s.\n" + CommandSubscribe
+	responseSubscribed    = "This chat is now subscribed to %s."
+	responseUnsubscribed  = "This chat is now unsubscribed from %s."
+	responseTopicsOverall = "Available topics:\n%s\nUse " + CommandSubscribe + " <topic> or " + CommandUnsubscribe + " <topic>."
+)
+
+// TopicStore writes the topic subscriptions of chats to a libkv store backend.
+type TopicStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewTopicStore stores topic subscriptions in the provided kv backend.
+func NewTopicStore(kv store.Store, storeKeyPrefix string) (*TopicStore, error) {
+	return &TopicStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Subscribers returns all chats subscribed to a topic.
+func (s *TopicStore) Subscribers(topic string) ([]*telebot.Chat, error) {
+	prefix := fmt.Sprintf("%s/%s/", s.storeKeyPrefix, topic)
+
+	kvPairs, err := s.kv.List(prefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var chats []*telebot.Chat
+	for _, kv := range kvPairs {
+		// Some backends match prefixes of other topics' names too.
+		if !strings.HasPrefix(strings.TrimPrefix(kv.Key, "/"), prefix) {
+			continue
+		}
+		var c *telebot.Chat
+		if err := json.Unmarshal(kv.Value, &c); err != nil {
+			return nil, err
+		}
+		chats = append(chats, c)
+	}
+
+	return chats, nil
+}
+
+// Subscribed returns whether a chat is subscribed to a topic.
+func (s *TopicStore) Subscribed(topic string, id telebot.ChatID) (bool, error) {
+	ok, err := s.kv.Exists(fmt.Sprintf("%s/%s/%d", s.storeKeyPrefix, topic, id))
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return false, err
+	}
+	return ok, nil
+}
+
+// Subscribe a telegram chat to a topic.
+func (s *TopicStore) Subscribe(topic string, c *telebot.Chat) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/%s/%d", s.storeKeyPrefix, topic, c.ID)
+
+	return s.kv.Put(key, b, nil)
+}
+
+// Unsubscribe a telegram chat from a topic.
+func (s *TopicStore) Unsubscribe(topic string, c *telebot.Chat) error {
+	key := fmt.Sprintf("%s/%s/%d", s.storeKeyPrefix, topic, c.ID)
+	if err := s.kv.Delete(key); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// isTopic returns whether topic is one of the configured topics.
+func (b *Bot) isTopic(topic string) bool {
+	i := sort.SearchStrings(b.topics, topic)
+	return i < len(b.topics) && b.topics[i] == topic
+}
+
+func (b *Bot) handleSubscribe(message *telebot.Message) error {
+	topic := strings.TrimSpace(message.Payload)
+	if topic == "" {
+		return b.sendTopics(message.Chat)
+	}
+	if !b.isTopic(topic) {
+		_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseTopicUnknown, topic))
+		return err
+	}
+
+	if err := b.topicStore.Subscribe(topic, message.Chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to subscribe chat to topic", "topic", topic, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't subscribe this chat to the topic.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "chat subscribed to topic",
+		"topic", topic,
+		"user_id", message.Sender.ID,
+		"chat_id", message.Chat.ID,
+	)
+
+	_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseSubscribed, topic))
+	return err
+}
+
+func (b *Bot) handleUnsubscribe(message *telebot.Message) error {
+	topic := strings.TrimSpace(message.Payload)
+	if topic == "" {
+		return b.sendTopics(message.Chat)
+	}
+	if !b.isTopic(topic) {
+		_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseTopicUnknown, topic))
+		return err
+	}
+
+	if err := b.topicStore.Unsubscribe(topic, message.Chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to unsubscribe chat from topic", "topic", topic, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't unsubscribe this chat from the topic.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "chat unsubscribed from topic",
+		"topic", topic,
+		"user_id", message.Sender.ID,
+		"chat_id", message.Chat.ID,
+	)
+
+	_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseUnsubscribed, topic))
+	return err
+}
+
+// sendTopics sends all topics to the chat, marking the ones it's subscribed to.
+func (b *Bot) sendTopics(chat *telebot.Chat) error {
+	if len(b.topics) == 0 {
+		_, err := b.telegram.Send(chat, responseTopicsNone)
+		return err
+	}
+
+	list := ""
+	for _, topic := range b.topics {
+		subscribed, err := b.topicStore.Subscribed(topic, telebot.ChatID(chat.ID))
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to read topic subscription", "topic", topic, "err", err)
+			_, err = b.telegram.Send(chat, "I can't list the topics.")
+			return err
+		}
+		if subscribed {
+			list = list + fmt.Sprintf("✅ %s\n", topic)
+		} else {
+			list = list + fmt.Sprintf("▫️ %s\n", topic)
+		}
+	}
+
+	_, err := b.telegram.Send(chat, fmt.Sprintf(responseTopicsOverall, list))
+	return err
+}
@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseTrustGroupOnly = "Only group chats can be trusted."
+	responseTrusted        = "All members of this chat are now %s."
+	responseUntrusted      = "Members of this chat don't get a role from it anymore."
+	responseTrustUsage     = "Usage: " + CommandTrust + " <viewer|operator>\nAdmins can only be granted one by one with " + CommandGrant + "."
+
+	// maxChatRole is the highest role members get from a trusted chat,
+	// so that no member can grant themselves admin with /grant.
+	maxChatRole = RoleOperator
+)
+
+// memberCache remembers whether users are members of chats for a while,
+// so that not every command needs to ask Telegram.
+type memberCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[memberKey]memberEntry
+}
+
+type memberKey struct {
+	chatID int64
+	userID int
+}
+
+type memberEntry struct {
+	member  bool
+	expires time.Time
+}
+
+func newMemberCache(ttl time.Duration) *memberCache {
+	return &memberCache{ttl: ttl, entries: map[memberKey]memberEntry{}}
+}
+
+func (c *memberCache) get(chatID int64, userID int) (member bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[memberKey{chatID: chatID, userID: userID}]
+	if !ok || time.Now().After(e.expires) {
+		return false, false
+	}
+	return e.member, true
+}
+
+func (c *memberCache) set(chatID int64, userID int, member bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[memberKey{chatID: chatID, userID: userID}] = memberEntry{
+		member:  member,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// reset forgets all cached memberships of a chat.
+func (c *memberCache) reset(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.entries {
+		if k.chatID == chatID {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// isGroup returns whether the chat is a group chat that can be trusted.
+func isGroup(chat *telebot.Chat) bool {
+	return chat.Type == telebot.ChatGroup || chat.Type == telebot.ChatSuperGroup
+}
+
+// chatRole returns the role the user has by being a member of a trusted chat.
+func (b *Bot) chatRole(chat *telebot.Chat, user *telebot.User) Role {
+	if b.trusted == nil || chat == nil || !isGroup(chat) {
+		return RoleNone
+	}
+
+	r, err := b.trusted.Get(chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get role of chat", "chat_id", chat.ID, "err", err)
+		return RoleNone
+	}
+	if r == RoleNone {
+		return RoleNone
+	}
+	if r > maxChatRole {
+		r = maxChatRole
+	}
+
+	member, ok := b.members.get(chat.ID, user.ID)
+	if !ok {
+		cm, err := b.telegram.ChatMemberOf(chat, user)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get chat member", "chat_id", chat.ID, "user_id", user.ID, "err", err)
+			return RoleNone
+		}
+		switch cm.Role {
+		case telebot.Creator, telebot.Administrator, telebot.Member, telebot.Restricted:
+			member = true
+		}
+		b.members.set(chat.ID, user.ID, member)
+	}
+
+	if !member {
+		return RoleNone
+	}
+	return r
+}
+
+func (b *Bot) handleTrust(message *telebot.Message) error {
+	if !isGroup(message.Chat) {
+		_, err := b.telegram.Send(message.Chat, responseTrustGroupOnly)
+		return err
+	}
+
+	r := RoleViewer
+	if name := strings.TrimSpace(message.Payload); name != "" {
+		var err error
+		r, err = ParseRole(name)
+		if err != nil || r > maxChatRole {
+			_, err = b.telegram.Send(message.Chat, responseTrustUsage)
+			return err
+		}
+	}
+
+	if b.trusted == nil {
+		_, err := b.telegram.Send(message.Chat, "I can't store trusted chats.")
+		return err
+	}
+	if err := b.trusted.Set(message.Chat.ID, r); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to store trusted chat", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't trust this chat.")
+		return err
+	}
+	b.members.reset(message.Chat.ID)
+
+	level.Info(b.logger).Log(
+		"msg", "chat trusted",
+		"chat_id", message.Chat.ID,
+		"role", r,
+		"trusted_by", message.Sender.ID,
+	)
+
+	_, err := b.telegram.Send(message.Chat, fmt.Sprintf(responseTrusted, r))
+	return err
+}
+
+func (b *Bot) handleUntrust(message *telebot.Message) error {
+	if !isGroup(message.Chat) {
+		_, err := b.telegram.Send(message.Chat, responseTrustGroupOnly)
+		return err
+	}
+
+	if b.trusted != nil {
+		if err := b.trusted.Remove(message.Chat.ID); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to remove trusted chat", "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't stop trusting this chat.")
+			return err
+		}
+		b.members.reset(message.Chat.ID)
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "chat untrusted",
+		"chat_id", message.Chat.ID,
+		"untrusted_by", message.Sender.ID,
+	)
+
+	_, err := b.telegram.Send(message.Chat, responseUntrusted)
+	return err
+}
@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	responseWatchdogMissing = "🐶 The %s alert didn't arrive for %s, the alerting pipeline might be broken!"
+	responseWatchdogBack    = "🐶 The %s alert arrives again, the alerting pipeline works."
+)
+
+// watchdogWebhook records the watchdog alert arriving and tells the admins
+// if it arrives again after it was missing.
+func (b *Bot) watchdogWebhook(m webhook.Message) {
+	if b.watchdogTimeout <= 0 {
+		level.Warn(b.logger).Log("msg", "received watchdog webhook without a watchdog timeout")
+		return
+	}
+	if !hasFiringAlert(m, b.watchdogAlert) {
+		level.Warn(b.logger).Log("msg", "watchdog webhook without the watchdog alert", "alert", b.watchdogAlert)
+		return
+	}
+
+	b.watchdogMu.Lock()
+	defer b.watchdogMu.Unlock()
+
+	b.watchdogSeen = time.Now()
+	level.Debug(b.logger).Log("msg", "watchdog alert arrived", "alert", b.watchdogAlert)
+
+	if !b.watchdogMissing {
+		return
+	}
+	b.watchdogMissing = false
+
+	level.Info(b.logger).Log("msg", "watchdog alert arrives again", "alert", b.watchdogAlert)
+	for _, id := range b.admins {
+		b.SendAdminMessage(id, fmt.Sprintf(responseWatchdogBack, b.watchdogAlert))
+	}
+}
+
+// checkWatchdog tells the admins once the watchdog alert didn't arrive for the watchdog timeout.
+func (b *Bot) checkWatchdog() {
+	b.watchdogMu.Lock()
+	defer b.watchdogMu.Unlock()
+
+	if b.watchdogMissing || time.Since(b.watchdogSeen) < b.watchdogTimeout {
+		return
+	}
+	b.watchdogMissing = true
+
+	level.Warn(b.logger).Log("msg", "watchdog alert missing", "alert", b.watchdogAlert)
+	for _, id := range b.admins {
+		b.SendAdminMessage(id, fmt.Sprintf(responseWatchdogMissing, b.watchdogAlert, model.Duration(b.watchdogTimeout)))
+	}
+}
+
+// hasFiringAlert returns whether the message has a firing alert with the alert name.
+func hasFiringAlert(m webhook.Message, name string) bool {
+	if m.Data == nil {
+		return false
+	}
+	for _, a := range m.Alerts {
+		if a.Status == string(model.AlertFiring) && a.Labels[string(model.AlertNameLabel)] == name {
+			return true
+		}
+	}
+	return false
+}
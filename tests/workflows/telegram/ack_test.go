@@ -0,0 +1,273 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	ackNotification = "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour"
+	// ackGroupID is the ID of webhookAck's alert group used by the acknowledge button.
+	ackGroupID = "7c64f33201637915"
+)
+
+func webhookAck(status string) []alertmanager.TelegramWebhook {
+	return []alertmanager.TelegramWebhook{{
+		ChatID: int64(admin.ID),
+		Message: webhook.Message{
+			Data: &template.Data{
+				Receiver: "telegram",
+				Status:   status,
+				Alerts: template.Alerts{{
+					Status:       status,
+					Labels:       template.KV{"alertname": "fire", "severity": "critical"},
+					Annotations:  template.KV{"message": "Something is on fire"},
+					StartsAt:     time.Now().Add(-time.Hour),
+					GeneratorURL: "http://localhost:9090/graph?g0.expr=vector%28666%29",
+					Fingerprint:  "a1b2c3d4e5f60718",
+				}},
+				GroupLabels:       template.KV{"alertname": "fire"},
+				CommonLabels:      template.KV{"alertname": "fire", "severity": "critical"},
+				CommonAnnotations: template.KV{"message": "Something is on fire"},
+				ExternalURL:       "http://localhost:9093",
+			},
+			Version:  "4",
+			GroupKey: `{}:{alertname="fire"}`,
+		},
+	}}
+}
+
+var ackWorkflows = []workflow{{
+	name:          "AckList",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   "Unacknowledged alerts:\na1b2c3d4e5f60718 fire\n\nAcknowledge with /ack <fingerprint>.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+	},
+}, {
+	name: "AckListNone",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "There are no unacknowledged alerts in this chat. 🎉",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+	},
+}, {
+	name:          "AckFingerprint",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " a1b2",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " a1b2",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   ackNotification + "\n\n✋ <b>Acked by</b> @elliot",
+	}, {
+		recipient: "123",
+		message:   "Acknowledged by @elliot.",
+	}, {
+		recipient: "123",
+		message:   "Already acknowledged by @elliot.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ack a1b2\"",
+		"level=info msg=\"alert group acknowledged\" group_key=\"{}:{alertname=\\\"fire\\\"}\" chat_id=123 user_id=123",
+		"level=debug msg=\"message received\" text=\"/ack a1b2\"",
+	},
+}, {
+	name:          "AckUnknownFingerprint",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " ffff",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   "I can't find a firing alert for ffff in this chat.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ack ffff\"",
+	},
+}, {
+	name:          "AckReply",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender:  admin,
+			Chat:    chatFromUser(admin),
+			Text:    telegram.CommandAck,
+			ReplyTo: &telebot.Message{ID: 1001},
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   ackNotification + "\n\n✋ <b>Acked by</b> @elliot",
+	}, {
+		recipient: "123",
+		message:   "Acknowledged by @elliot.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+		"level=info msg=\"alert group acknowledged\" group_key=\"{}:{alertname=\\\"fire\\\"}\" chat_id=123 user_id=123",
+	},
+}, {
+	name:          "AckButton",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	messages: []telebot.Update{{
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1001, Chat: chatFromUser(admin)},
+			Data:    "\fack|" + ackGroupID,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   ackNotification + "\n\n✋ <b>Acked by</b> @elliot",
+	}, {
+		recipient: "callback",
+		message:   "Acknowledged by @elliot.",
+	}},
+	counter: map[string]uint{"button:ack": 1},
+	logs: []string{
+		"level=info msg=\"alert group acknowledged\" group_key=\"{}:{alertname=\\\"fire\\\"}\" chat_id=123 user_id=123",
+	},
+}, {
+	name:          "AckResolved",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return append(webhookAck("firing"), webhookAck("resolved")...)
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " a1b2",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		pattern:   "^✅ <b>fire</b> ✅",
+	}, {
+		recipient: "123",
+		message:   "I can't find a firing alert for a1b2 in this chat.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ack a1b2\"",
+	},
+}, {
+	name:       "AckReminder",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithAckReminder(time.Millisecond),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   "⏰ This alert is still firing and nobody acknowledged it yet.\n/ack",
+	}},
+	logs: []string{""},
+}, {
+	name:       "AckNotificationExpired",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithNotificationMaxAge(time.Millisecond),
+		telegram.WithSchedulerInterval(time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}},
+	logs: []string{
+		"level=info msg=\"notification expired\" chat_id=123 group_key=\"{}:{alertname=\\\"fire\\\"}\"",
+	},
+}}
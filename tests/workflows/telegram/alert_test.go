@@ -0,0 +1,170 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func alertmanagerAlertDetails(t *testing.T, r *http.Request) string {
+	require.Equal(t, "", r.URL.Query().Get("receiver"))
+	require.Equal(t, "true", r.URL.Query().Get("silenced"))
+
+	return fmt.Sprintf(`[{
+		"labels":{"alertname":"Watchdog","severity":"none"},
+		"annotations":{"message":"Everything <works>"},
+		"fingerprint":"7a90bbdd1d39f61b",
+		"receivers":[{"name":"healthcheck"},{"name":"admin"}],
+		"status":{"state":"suppressed","silencedBy":["34f5f82b"],"inhibitedBy":[]},
+		"generatorURL":"https://prometheus.io/graph?g0.expr=vector%%281%%29",
+		"startsAt":"%s"
+	}, {
+		"labels":{"alertname":"NodeDown","instance":"node-1"},
+		"fingerprint":"7a91aaaa00000000",
+		"status":{"state":"active"},
+		"startsAt":"%s"
+	}]`,
+		time.Now().Add(-time.Hour).Format(time.RFC3339Nano),
+		time.Now().Add(-time.Hour).Format(time.RFC3339Nano),
+	)
+}
+
+var alertWorkflows = []workflow{{
+	name: "AlertUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlert,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /alert <fingerprint>",
+	}},
+	counter: map[string]uint{telegram.CommandAlert: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/alert",
+	},
+}, {
+	name: "AlertNotFound",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlert + " ffff",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "There is no alert with a fingerprint starting with ffff.",
+	}},
+	counter: map[string]uint{telegram.CommandAlert: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/alert ffff\"",
+	},
+	alertmanagerAlerts: alertmanagerAlertDetails,
+}, {
+	name: "AlertAmbiguous",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlert + " 7a9",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "7a9 matches more than one alert:\n7a90bbdd1d39f61b Watchdog\n7a91aaaa00000000 NodeDown",
+	}},
+	counter: map[string]uint{telegram.CommandAlert: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/alert 7a9\"",
+	},
+	alertmanagerAlerts: alertmanagerAlertDetails,
+}, {
+	name: "AlertDetails",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlert + " 7a90",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern: "^🔕 <b>Watchdog</b> 🔕\n" +
+			"<b>Fingerprint:</b> <code>7a90bbdd1d39f61b</code>\n" +
+			"<b>State:</b> suppressed\n" +
+			"<b>Labels:</b>\n    alertname: Watchdog\n    severity: none\n" +
+			"<b>Annotations:</b>\n    message: Everything &lt;works&gt;\n" +
+			"<b>Started:</b> 1 hour( \\d+ seconds?)? ago\n" +
+			"<b>Silenced by:</b> 34f5f82b\n" +
+			"<b>Receivers:</b> healthcheck, admin\n" +
+			"<a href=\"https://prometheus\\.io/graph\\?g0\\.expr=vector%281%29\">Source</a>$",
+	}},
+	counter: map[string]uint{telegram.CommandAlert: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/alert 7a90\"",
+	},
+	alertmanagerAlerts: alertmanagerAlertDetails,
+}, {
+	name: "AlertSilenceButton",
+	messages: []telebot.Update{{
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1, Chat: chatFromUser(admin)},
+			Data:    "\fsilence|7a90bbdd1d39f61b 4h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "callback",
+		message:   "🔕 @elliot silenced Watchdog for 4h.",
+	}, {
+		recipient: "123",
+		message:   "🔕 @elliot silenced Watchdog for 4h.",
+	}},
+	counter: map[string]uint{"button:silence": 1},
+	logs: []string{
+		"level=info msg=\"alert silenced\" fingerprint=7a90bbdd1d39f61b silence_id=34f5f82b duration=4h user_id=123",
+	},
+	alertmanagerAlerts: alertmanagerAlertDetails,
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, http.MethodPost, r.Method)
+
+		var s models.PostableSilence
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&s))
+		require.Equal(t, "@elliot", *s.CreatedBy)
+		require.Len(t, s.Matchers, 2)
+		require.Equal(t, "alertname", *s.Matchers[0].Name)
+		require.Equal(t, "Watchdog", *s.Matchers[0].Value)
+		require.Equal(t, 4*time.Hour, time.Time(*s.EndsAt).Sub(time.Time(*s.StartsAt)).Round(time.Minute))
+
+		return `{"silenceID":"34f5f82b"}`
+	},
+}, {
+	name: "AlertSilenceButtonForbidden",
+	messages: []telebot.Update{{
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  nobody,
+			Message: &telebot.Message{ID: 1, Chat: chatFromUser(nobody)},
+			Data:    "\fsilence|7a90bbdd1d39f61b 4h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "callback",
+		message:   "Sorry, you're not allowed to use this command.",
+	}},
+	logs: []string{
+		"level=info msg=\"dropping button press from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}}
@@ -3,6 +3,7 @@ package telegram
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -139,7 +140,7 @@ var alertsWorkflows = []workflow{{
 	}},
 	replies: []reply{{
 		recipient: "123",
-		message:   "This chat hasn't been setup to receive any alerts yet... 😕\n\nAsk an administrator of the Alertmanager to add a webhook with `/webhooks/telegram/123` as URL.",
+		message:   "This chat hasn't been setup to receive any alerts yet... 😕\n\nAsk an administrator of the Alertmanager to add a webhook with `/webhooks/telegram/123` as URL or an admin of this bot to bind this chat with `/receiver set <receiver>`.",
 	}},
 	counter: map[string]uint{telegram.CommandAlerts: 1},
 	logs: []string{
@@ -148,4 +149,95 @@ var alertsWorkflows = []workflow{{
 	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
 		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\n  webhook_configs:\n  - send_resolved: true\n    url: http://localhost:8080/webhooks/telegram/unknown"}}`
 	},
+}, {
+	name: "AlertsFilter",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlerts + " job=~api.* inhibited sort:severity",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^🔥 <b>critical</b> 🔥(.|\\n)*🔥 <b>warning</b> 🔥(.|\\n)*🔥 <b>other</b> 🔥",
+	}},
+	counter: map[string]uint{telegram.CommandAlerts: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/alerts job=~api.* inhibited sort:severity\"",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, "false", r.URL.Query().Get("active"))
+		require.Equal(t, "true", r.URL.Query().Get("inhibited"))
+		require.Equal(t, "false", r.URL.Query().Get("silenced"))
+		require.Equal(t, []string{"job=~api.*"}, r.URL.Query()["filter"])
+
+		return fmt.Sprintf(
+			`[{"labels":{"alertname":"other"},"startsAt":"%[1]s"},{"labels":{"alertname":"warning","severity":"warning"},"startsAt":"%[1]s"},{"labels":{"alertname":"critical","severity":"critical"},"startsAt":"%[1]s"}]`,
+			time.Now().Add(-time.Hour).Format(time.RFC3339),
+		)
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\n  webhook_configs:\n  - send_resolved: true\n    url: http://localhost:8080/webhooks/telegram/123"}}`
+	},
+}, {
+	name: "AlertsInvalidMatcher",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlerts + " severity",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^bad matcher format: severity\\nUsage: /alerts \\[all\\] \\[matchers...\\]",
+	}},
+	counter: map[string]uint{telegram.CommandAlerts: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/alerts severity\"",
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\n  webhook_configs:\n  - send_resolved: true\n    url: http://localhost:8080/webhooks/telegram/123"}}`
+	},
+}, {
+	name: "AlertsPages",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlerts,
+		},
+	}, {
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: chatFromUser(admin)},
+			Data:    "\falerts|1 1",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^🔥 <b>alert00</b> 🔥(.|\\n)*🔥 <b>alert09</b> 🔥(.|\\n)*<i>Page 1 of 2, 12 alerts</i>$",
+	}, {
+		recipient: "123",
+		pattern:   "^🔥 <b>alert10</b> 🔥(.|\\n)*🔥 <b>alert11</b> 🔥(.|\\n)*<i>Page 2 of 2, 12 alerts</i>$",
+	}},
+	counter: map[string]uint{telegram.CommandAlerts: 1, "button:alerts": 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/alerts",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		alerts := make([]string, 0, 12)
+		for i := 0; i < 12; i++ {
+			alerts = append(alerts, fmt.Sprintf(
+				`{"labels":{"alertname":"alert%02d"},"startsAt":"%s"}`,
+				i, time.Now().Add(-time.Duration(i+1)*time.Minute).Format(time.RFC3339),
+			))
+		}
+		return "[" + strings.Join(alerts, ",") + "]"
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\n  webhook_configs:\n  - send_resolved: true\n    url: http://localhost:8080/webhooks/telegram/123"}}`
+	},
 }}
@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var auditWorkflows = []workflow{{
+	name: "AuditEmpty",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAudit,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "The audit log is empty.",
+	}},
+	counter: map[string]uint{telegram.CommandAudit: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/audit",
+	},
+}, {
+	name: "AuditCommands",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGrant + " 222 viewer",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandChats,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandStop,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAudit + " 2",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "User 222 is now viewer.",
+	}, {
+		recipient: "222",
+		message:   "Sorry, you're not allowed to use this command.",
+	}, {
+		recipient: "222",
+		message:   "Sorry, you're not allowed to use this command.",
+	}, {
+		recipient: "123",
+		pattern: `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} @nobody \(222\) in 222: /chats → forbidden\n` +
+			`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} @elliot \(123\) in 123: /grant 222 viewer → ok$`,
+	}},
+	counter: map[string]uint{
+		telegram.CommandGrant: 1,
+		telegram.CommandAudit: 1,
+	},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/grant 222 viewer\"",
+		"level=info msg=\"role granted\" user_id=222 role=viewer granted_by=123",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+		"level=debug msg=\"message received\" text=\"/audit 2\"",
+	},
+}}
@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var dedupWorkflows = []workflow{{
+	name:       "Dedup",
+	subscribed: []*telebot.Chat{chatFromUser(admin), {ID: -1234, Type: telebot.ChatGroup}},
+	options: []telegram.BotOption{
+		telegram.WithDeduplication(time.Hour),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		// Both replicas of an HA pair notify, the group chat receives the same alert group too.
+		webhooks := append(webhookAck("firing"), webhookAck("firing")...)
+		group := webhookAck("firing")
+		group[0].ChatID = -1234
+		webhooks = append(webhooks, group...)
+		return append(webhooks, webhookAck("resolved")...)
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "-1234",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		pattern:   "^✅",
+	}},
+	logs: []string{
+		"level=debug msg=\"dropped duplicate notification\" group=" + ackGroupID,
+	},
+}, {
+	name:       "DedupRefiring",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithDeduplication(time.Hour),
+	},
+	// The alert group fires again after it resolved, only the replica's copy of the first firing webhook is dropped.
+	webhooks: webhooksFlapping("firing", "firing", "resolved", "firing"),
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		pattern:   "^✅",
+	}, {
+		recipient: "123",
+		message:   ackNotification,
+	}},
+	logs: []string{
+		"level=debug msg=\"dropped duplicate notification\" group=" + ackGroupID,
+	},
+}}
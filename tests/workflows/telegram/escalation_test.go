@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	escalationGroup = &telebot.Chat{ID: -1234, Type: telebot.ChatGroup, Title: "On-Call"}
+
+	escalationPolicy = telegram.EscalationPolicy{
+		Name:   "critical",
+		Labels: map[string]string{"severity": "critical"},
+		Steps:  []telegram.EscalationStep{{After: 40 * time.Millisecond, Chat: escalationGroup.ID}},
+	}
+)
+
+var escalationWorkflows = []workflow{{
+	name:       "Escalation",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithEscalationPolicies(escalationPolicy),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "-1234",
+		pattern:   "^⬆️ <b>Escalated</b> from @elliot, nobody acknowledged it for .*\\.\n\n🔥 <b>fire</b> 🔥",
+	}},
+	logs: []string{
+		"level=info msg=\"alert group escalated\" policy=critical group_key=\"{}:{alertname=\\\"fire\\\"}\" from=123 to=-1234",
+	},
+}, {
+	name:        "EscalationRetried",
+	subscribed:  []*telebot.Chat{chatFromUser(admin)},
+	failedSends: map[string]int{"-1234": 1},
+	options: []telegram.BotOption{
+		telegram.WithEscalationPolicies(telegram.EscalationPolicy{
+			Name:   "critical",
+			Labels: map[string]string{"severity": "critical"},
+			Steps:  []telegram.EscalationStep{{After: time.Millisecond, Chat: escalationGroup.ID}},
+		}),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "-1234",
+		pattern:   "^⬆️ <b>Escalated</b> from @elliot, nobody acknowledged it for .*\\.\n\n🔥 <b>fire</b> 🔥",
+	}},
+	logs: []string{
+		"level=warn msg=\"failed to escalate alert group\" policy=critical chat_id=-1234 err=\"chat not found\"",
+		"level=info msg=\"alert group escalated\" policy=critical group_key=\"{}:{alertname=\\\"fire\\\"}\" from=123 to=-1234",
+	},
+}, {
+	name:        "EscalationSkipped",
+	subscribed:  []*telebot.Chat{chatFromUser(admin)},
+	failedSends: map[string]int{"-1234": 10},
+	options: []telegram.BotOption{
+		telegram.WithEscalationPolicies(telegram.EscalationPolicy{
+			Name:   "critical",
+			Labels: map[string]string{"severity": "critical"},
+			Steps: []telegram.EscalationStep{
+				{After: time.Millisecond, Chat: escalationGroup.ID},
+				{After: 2 * time.Millisecond, Chat: -5678},
+			},
+		}),
+		telegram.WithSchedulerInterval(10 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "-5678",
+		pattern:   "^⬆️ <b>Escalated</b> from @elliot, nobody acknowledged it for .*\\.\n\n🔥 <b>fire</b> 🔥",
+	}},
+	logs: []string{
+		"level=warn msg=\"failed to escalate alert group\" policy=critical chat_id=-1234 err=\"chat not found\"",
+		"level=warn msg=\"failed to escalate alert group\" policy=critical chat_id=-1234 err=\"chat not found\"",
+		"level=warn msg=\"failed to escalate alert group\" policy=critical chat_id=-1234 err=\"chat not found\"",
+		"level=warn msg=\"skipped escalation step\" policy=critical chat_id=-1234 attempts=3",
+		"level=info msg=\"alert group escalated\" policy=critical group_key=\"{}:{alertname=\\\"fire\\\"}\" from=123 to=-5678",
+	},
+}, {
+	name:          "EscalationAcked",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	options: []telegram.BotOption{
+		telegram.WithEscalationPolicies(escalationPolicy),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " a1b2",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   ackNotification + "\n\n✋ <b>Acked by</b> @elliot",
+	}, {
+		recipient: "123",
+		message:   "Acknowledged by @elliot.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ack a1b2\"",
+		"level=info msg=\"alert group acknowledged\" group_key=\"{}:{alertname=\\\"fire\\\"}\" chat_id=123 user_id=123",
+	},
+}, {
+	name:       "EscalationNotMatching",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithEscalationPolicies(telegram.EscalationPolicy{
+			Name:   "warning",
+			Labels: map[string]string{"severity": "warning"},
+			Steps:  []telegram.EscalationStep{{After: time.Millisecond, Chat: escalationGroup.ID}},
+		}),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookAck("firing")
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}},
+	logs: []string{""},
+}}
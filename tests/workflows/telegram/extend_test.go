@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const extendSilenceID = "34f5f82b-b66f-456b-aff7-b556a7eafe81"
+
+// alertmanagerExtendSilence returns the silence ending in an hour and expects it to be re-posted ending in 3 hours.
+func alertmanagerExtendSilence(t *testing.T, r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet:
+		require.Equal(t, "/api/v2/silence/"+extendSilenceID, r.URL.Path)
+		return alertmanagerSilence(extendSilenceID, "darlene", "node-1", "active", time.Hour)
+	case http.MethodPost:
+		var s models.PostableSilence
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&s))
+		require.Equal(t, extendSilenceID, s.ID)
+		require.Equal(t, "darlene", *s.CreatedBy)
+		require.Len(t, s.Matchers, 2)
+		require.Equal(t, 3*time.Hour, time.Until(time.Time(*s.EndsAt)).Round(time.Minute))
+		return `{"silenceID":"` + extendSilenceID + `"}`
+	}
+	return ""
+}
+
+var extendWorkflows = []workflow{{
+	name: "Extend",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandExtend + " " + extendSilenceID + " 2h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^⏳ @elliot extended the silence <code>" + extendSilenceID + "</code> by 2h, it now ends at \\d{4}-\\d{2}-\\d{2} \\d{2}:\\d{2} UTC.$",
+	}},
+	counter: map[string]uint{telegram.CommandExtend: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/extend " + extendSilenceID + " 2h\"",
+		"level=info msg=\"silence extended\" silence_id=" + extendSilenceID + " duration=2h user_id=123",
+	},
+	alertmanagerSilences: alertmanagerExtendSilence,
+}, {
+	name: "ExtendButton",
+	messages: []telebot.Update{{
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: chatFromUser(admin)},
+			Data:    "\fextend|" + extendSilenceID + " 2h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^⏳ @elliot extended the silence <code>" + extendSilenceID + "</code> by 2h, it now ends at .* UTC.$",
+	}},
+	counter: map[string]uint{"button:extend": 1},
+	logs: []string{
+		"level=info msg=\"silence extended\" silence_id=" + extendSilenceID + " duration=2h user_id=123",
+	},
+	alertmanagerSilences: alertmanagerExtendSilence,
+}, {
+	name: "ExtendExpired",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandExtend + " " + extendSilenceID + " 2h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "The silence " + extendSilenceID + " has expired already.",
+	}},
+	counter: map[string]uint{telegram.CommandExtend: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/extend " + extendSilenceID + " 2h\"",
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, http.MethodGet, r.Method)
+		return alertmanagerSilence(extendSilenceID, "darlene", "node-1", "expired", -time.Hour)
+	},
+}, {
+	name: "ExtendNotFound",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandExtend + " " + extendSilenceID + " 2h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "There is no silence with ID " + extendSilenceID + ".",
+	}},
+	counter: map[string]uint{telegram.CommandExtend: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/extend " + extendSilenceID + " 2h\"",
+	},
+}, {
+	name: "ExtendUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandExtend + " 34f5f82b 2h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /extend <silence-id> <duration>\nFor example: /extend 34f5f82b-b66f-456b-aff7-b556a7eafe81 2h",
+	}},
+	counter: map[string]uint{telegram.CommandExtend: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/extend 34f5f82b 2h\"",
+	},
+}}
@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// webhooksFlapping notifies about the alert group firing and resolving in turns.
+func webhooksFlapping(statuses ...string) func() []alertmanager.TelegramWebhook {
+	return func() []alertmanager.TelegramWebhook {
+		var webhooks []alertmanager.TelegramWebhook
+		for _, status := range statuses {
+			webhooks = append(webhooks, webhookAck(status)...)
+		}
+		return webhooks
+	}
+}
+
+var flappingWorkflows = []workflow{{
+	name:       "Flapping",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithFlapDetection(2, time.Hour),
+	},
+	webhooks: webhooksFlapping("firing", "resolved", "firing", "resolved", "firing"),
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		pattern:   "^✅",
+	}, {
+		recipient: "123",
+		message:   "🔀 <b>fire</b> is flapping, it changed 3 times in the last 1h and is firing now.\nI'll only update this message until it's stable again.",
+	}, {
+		recipient: "123",
+		message:   "🔀 <b>fire</b> is flapping, it changed 4 times in the last 1h and is resolved now.\nI'll only update this message until it's stable again.",
+	}, {
+		recipient: "123",
+		message:   "🔀 <b>fire</b> is flapping, it changed 5 times in the last 1h and is firing now.\nI'll only update this message until it's stable again.",
+	}},
+	logs: []string{
+		"level=info msg=\"alert group flapping\" group=" + ackGroupID + " changes=3",
+	},
+}, {
+	name:       "FlappingStable",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithFlapDetection(2, 40*time.Millisecond),
+		telegram.WithSchedulerInterval(10 * time.Millisecond),
+	},
+	webhooks: webhooksFlapping("firing", "resolved", "firing"),
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		pattern:   "^✅",
+	}, {
+		recipient: "123",
+		pattern:   "^🔀 <b>fire</b> is flapping, it changed 3 times",
+	}, {
+		recipient: "123",
+		message:   "✅ <b>fire</b> stopped flapping and is firing now.\nI'll notify about it as usual again.",
+	}},
+	logs: []string{
+		"level=info msg=\"alert group flapping\" group=" + ackGroupID + " changes=3",
+		"level=info msg=\"alert group stopped flapping\" group=" + ackGroupID,
+	},
+}, {
+	// The stable alert group is forgotten, so its next changes are counted from scratch.
+	name:       "FlappingStableAgain",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithFlapDetection(2, 40*time.Millisecond),
+		telegram.WithSchedulerInterval(10 * time.Millisecond),
+	},
+	webhooks:      webhooksFlapping("firing", "resolved", "firing"),
+	laterWebhooks: webhooksFlapping("firing", "resolved"),
+	laterDelay:    100 * time.Millisecond,
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		pattern:   "^✅",
+	}, {
+		recipient: "123",
+		pattern:   "^🔀 <b>fire</b> is flapping, it changed 3 times",
+	}, {
+		recipient: "123",
+		message:   "✅ <b>fire</b> stopped flapping and is firing now.\nI'll notify about it as usual again.",
+	}, {
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		pattern:   "^✅",
+	}},
+	logs: []string{
+		"level=info msg=\"alert group flapping\" group=" + ackGroupID + " changes=3",
+		"level=info msg=\"alert group stopped flapping\" group=" + ackGroupID,
+	},
+}, {
+	// The notification of the firing alert group isn't reminded of after it resolved while flapping.
+	name:       "FlappingResolved",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithFlapDetection(1, time.Hour),
+		telegram.WithAckReminder(time.Millisecond),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	webhooks: webhooksFlapping("firing", "resolved"),
+	replies: []reply{{
+		recipient: "123",
+		message:   ackNotification,
+	}, {
+		recipient: "123",
+		message:   "🔀 <b>fire</b> is flapping, it changed 2 times in the last 1h and is resolved now.\nI'll only update this message until it's stable again.",
+	}},
+	logs: []string{
+		"level=info msg=\"alert group flapping\" group=" + ackGroupID + " changes=2",
+	},
+}}
@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var groupsWorkflows = []workflow{{
+	name: "Groups",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroups + " severity=critical",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Alert groups:</b> 2\n\n" +
+			"<code>{alertname=&#34;DiskFull&#34;}</code> → admin\n    1 alert\n\n" +
+			"<code>{alertname=&#34;NodeDown&#34;}</code> → admin\n    2 alerts, 1 suppressed",
+	}},
+	counter: map[string]uint{telegram.CommandGroups: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/groups severity=critical\"",
+	},
+	alertmanagerGroups: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, "admin", r.URL.Query().Get("receiver"))
+		require.Equal(t, []string{"severity=critical"}, r.URL.Query()["filter"])
+		return `[{
+			"labels": {"alertname": "NodeDown"},
+			"receiver": {"name": "admin"},
+			"alerts": [{
+				"labels": {"alertname": "NodeDown", "instance": "a"},
+				"fingerprint": "c000000000000001",
+				"status": {"state": "active", "silencedBy": [], "inhibitedBy": []},
+				"receivers": [{"name": "admin"}],
+				"startsAt": "2021-01-01T00:00:00Z", "updatedAt": "2021-01-01T00:00:00Z", "endsAt": "2099-01-01T00:00:00Z"
+			}, {
+				"labels": {"alertname": "NodeDown", "instance": "b"},
+				"fingerprint": "c000000000000002",
+				"status": {"state": "suppressed", "silencedBy": ["34f5f82b"], "inhibitedBy": []},
+				"receivers": [{"name": "admin"}],
+				"startsAt": "2021-01-01T00:00:00Z", "updatedAt": "2021-01-01T00:00:00Z", "endsAt": "2099-01-01T00:00:00Z"
+			}]
+		}, {
+			"labels": {"alertname": "DiskFull"},
+			"receiver": {"name": "admin"},
+			"alerts": [{
+				"labels": {"alertname": "DiskFull"},
+				"fingerprint": "c000000000000003",
+				"status": {"state": "active", "silencedBy": [], "inhibitedBy": []},
+				"receivers": [{"name": "admin"}],
+				"startsAt": "2021-01-01T00:00:00Z", "updatedAt": "2021-01-01T00:00:00Z", "endsAt": "2099-01-01T00:00:00Z"
+			}]
+		}]`
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\n  webhook_configs:\n  - send_resolved: true\n    url: http://localhost:8080/webhooks/telegram/123"}}`
+	},
+}, {
+	name: "GroupsNone",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroups + " all",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "No alert groups right now! 🎉",
+	}},
+	counter: map[string]uint{telegram.CommandGroups: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/groups all\"",
+	},
+	alertmanagerGroups: func(t *testing.T, r *http.Request) string {
+		require.Empty(t, r.URL.Query().Get("receiver"))
+		return `[]`
+	},
+}}
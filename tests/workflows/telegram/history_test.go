@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// webhooksHistory notifies about the alert firing twice and resolving.
+func webhooksHistory() []alertmanager.TelegramWebhook {
+	startsAt := time.Now().Add(-time.Hour)
+
+	firing := webhookAck("firing")
+	firing[0].Message.Alerts[0].StartsAt = startsAt
+	again := webhookAck("firing")
+	again[0].Message.Alerts[0].StartsAt = startsAt
+	resolved := webhookAck("resolved")
+	resolved[0].Message.Alerts[0].StartsAt = startsAt
+	resolved[0].Message.Alerts[0].EndsAt = time.Now().Add(-10 * time.Minute)
+
+	return append(append(firing, again...), resolved...)
+}
+
+var historyWorkflows = []workflow{{
+	name:          "History",
+	subscribed:    []*telebot.Chat{chatFromUser(admin)},
+	webhooksFirst: true,
+	webhooks:      webhooksHistory,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandHistory,
+		},
+	}},
+	replies: []reply{
+		{recipient: "123", pattern: "^🔥"},
+		{recipient: "123", pattern: "^🔥"},
+		{recipient: "123", pattern: "^✅"},
+		{
+			recipient: "123",
+			pattern: "^<b>History of the last 1d:</b>\n" +
+				".* UTC ✅ fire <code>a1b2c3d4e5f60718</code>\n" +
+				".* UTC 🔥 fire <code>a1b2c3d4e5f60718</code>\n\n" +
+				"<b>Fired in the last 1w:</b>\n" +
+				"fire: 1 time$",
+		},
+	},
+	counter: map[string]uint{telegram.CommandHistory: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/history",
+	},
+}, {
+	name:          "HistoryMatchers",
+	webhooksFirst: true,
+	webhooks:      webhooksHistory,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandHistory + " alertname=other 3d",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandHistory + " alertname=~[",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Nothing fired or resolved in the last 3d.",
+	}, {
+		recipient: "123",
+		pattern:   "Usage: /history \\[matchers...\\] \\[since\\]\nFor example: /history alertname=NodeDown 3d$",
+	}},
+	counter: map[string]uint{telegram.CommandHistory: 2},
+	logs: []string{
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=123 err=\"chat not found in store\"",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=123 err=\"chat not found in store\"",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=123 err=\"chat not found in store\"",
+		"level=debug msg=\"message received\" text=\"/history alertname=other 3d\"",
+		"level=debug msg=\"message received\" text=\"/history alertname=~[\"",
+	},
+}}
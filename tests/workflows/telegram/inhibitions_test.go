@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var inhibitionsWorkflows = []workflow{{
+	name: "Inhibitions",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandInhibitions,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Inhibit rules:</b>\n" +
+			"1. <code>{severity=&#34;critical&#34;}</code> inhibits <code>{severity=~&#34;^(?:warning|info)$&#34;}</code> with equal alertname, cluster\n" +
+			"\n<b>Inhibited alerts:</b>\n" +
+			"Unknown alert <code>0000000000000000</code> inhibits:\n" +
+			"    ↳ DiskFull <code>c000000000000003</code>\n" +
+			"NodeDown <code>c000000000000001</code> inhibits:\n" +
+			"    ↳ HighLatency <code>c000000000000002</code>\n" +
+			"    ↳ DiskFull <code>c000000000000003</code>",
+	}},
+	counter: map[string]uint{telegram.CommandInhibitions: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/inhibitions",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, "true", r.URL.Query().Get("inhibited"))
+		return `[{
+			"labels":{"alertname":"NodeDown","severity":"critical"},
+			"fingerprint":"c000000000000001",
+			"status":{"state":"active","silencedBy":[],"inhibitedBy":[]},
+			"receivers":[{"name":"admin"}],
+			"startsAt":"2021-01-01T00:00:00Z","updatedAt":"2021-01-01T00:00:00Z","endsAt":"2099-01-01T00:00:00Z"
+		}, {
+			"labels":{"alertname":"HighLatency","severity":"warning"},
+			"fingerprint":"c000000000000002",
+			"status":{"state":"suppressed","silencedBy":[],"inhibitedBy":["c000000000000001"]},
+			"receivers":[{"name":"admin"}],
+			"startsAt":"2021-01-01T00:00:00Z","updatedAt":"2021-01-01T00:00:00Z","endsAt":"2099-01-01T00:00:00Z"
+		}, {
+			"labels":{"alertname":"DiskFull","severity":"info"},
+			"fingerprint":"c000000000000003",
+			"status":{"state":"suppressed","silencedBy":[],"inhibitedBy":["c000000000000001","0000000000000000"]},
+			"receivers":[{"name":"admin"}],
+			"startsAt":"2021-01-01T00:00:00Z","updatedAt":"2021-01-01T00:00:00Z","endsAt":"2099-01-01T00:00:00Z"
+		}]`
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\ninhibit_rules:\n` +
+			`- source_match:\n    severity: critical\n  target_match_re:\n    severity: warning|info\n  equal: [alertname, cluster]"}}`
+	},
+}, {
+	name: "InhibitionsNone",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandInhibitions,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "<b>Inhibit rules:</b>\nThere are no inhibit rules.\n\n<b>Inhibited alerts:</b>\nNo alerts are inhibited right now.",
+	}},
+	counter: map[string]uint{telegram.CommandInhibitions: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/inhibitions",
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin"}}`
+	},
+}}
@@ -0,0 +1,252 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const maintenanceSilenceID = "5e8a1c2f-9b7d-4f3e-8a6c-1d2e3f4a5b6c"
+
+var maintenanceWorkflows = []workflow{{
+	name: "MaintenanceNone",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "There are no maintenance windows.\n" +
+			"Usage: /maintenance <start> <end> [every:<interval>] <matchers...>\n" +
+			"For example: /maintenance 2021-03-07T02:00:00Z 2021-03-07T04:00:00Z every:1w cluster=eu1\n" +
+			"Delete a maintenance window with /maintenance delete <id>.",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/maintenance",
+	},
+}, {
+	name: "MaintenanceSaveList",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " 2099-03-01T02:00:00Z 2099-03-01T04:00:00Z every:1w cluster=eu1",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🔧 Maintenance window <code>ccfd6f3c</code> saved, next from 2099-03-01 02:00 UTC to 2099-03-01 04:00 UTC.",
+	}, {
+		recipient: "123",
+		message: "<b>Maintenance windows:</b>\n" +
+			"<code>ccfd6f3c</code> <code>cluster=eu1</code>\n" +
+			"    2099-03-01 02:00 UTC to 2099-03-01 04:00 UTC, every 1w by @elliot",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/maintenance 2099-03-01T02:00:00Z 2099-03-01T04:00:00Z every:1w cluster=eu1\"",
+		"level=info msg=\"maintenance window saved\" id=ccfd6f3c every=1w saved_by=123",
+		"level=debug msg=\"message received\" text=/maintenance",
+	},
+}, {
+	name: "MaintenanceDuplicate",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " 2099-03-01T02:00:00Z 2099-03-01T04:00:00Z every:1w cluster=eu1",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " 2099-03-01T02:00:00Z 2099-03-01T04:00:00Z every:1w cluster=eu1",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🔧 Maintenance window <code>ccfd6f3c</code> saved, next from 2099-03-01 02:00 UTC to 2099-03-01 04:00 UTC.",
+	}, {
+		recipient: "123",
+		message:   "The maintenance window <code>ccfd6f3c</code> exists already, delete it first to change it.",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/maintenance 2099-03-01T02:00:00Z 2099-03-01T04:00:00Z every:1w cluster=eu1\"",
+		"level=info msg=\"maintenance window saved\" id=ccfd6f3c every=1w saved_by=123",
+		"level=debug msg=\"message received\" text=\"/maintenance 2099-03-01T02:00:00Z 2099-03-01T04:00:00Z every:1w cluster=eu1\"",
+	},
+}, {
+	name: "MaintenanceInvalid",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " 2099-03-01T04:00:00Z 2099-03-01T02:00:00Z cluster=eu1",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " delete 0000",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^the end needs to be after the start\nUsage: /maintenance ",
+	}, {
+		recipient: "123",
+		message:   "There is no maintenance window 0000.",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/maintenance 2099-03-01T04:00:00Z 2099-03-01T02:00:00Z cluster=eu1\"",
+		"level=debug msg=\"message received\" text=\"/maintenance delete 0000\"",
+	},
+}, {
+	// The window repeats daily and is always active, except for its last minute before starting again.
+	name: "MaintenanceSilence",
+	options: []telegram.BotOption{
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " 2021-01-01T00:00:00Z 2021-01-01T23:59:00Z every:1d job=backup",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^🔧 Maintenance window <code>95d60967</code> saved, next from ",
+	}, {
+		recipient: "123",
+		pattern:   "^🔧 The maintenance window <code>95d60967</code> starts (now|in 1 minute), I silenced <code>{job=&#34;backup&#34;}</code> until \\d{4}-\\d{2}-\\d{2} 23:59 UTC.$",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/maintenance 2021-01-01T00:00:00Z 2021-01-01T23:59:00Z every:1d job=backup\"",
+		"level=info msg=\"maintenance window saved\" id=95d60967 every=1d saved_by=123",
+		"level=info msg=\"maintenance window silenced\" id=95d60967 silence_id=" + maintenanceSilenceID,
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, http.MethodPost, r.Method)
+		var s models.PostableSilence
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&s))
+		require.Equal(t, "@elliot", *s.CreatedBy)
+		require.Equal(t, "Maintenance window 95d60967", *s.Comment)
+		require.Len(t, s.Matchers, 1)
+		require.Equal(t, 23*time.Hour+59*time.Minute, time.Time(*s.EndsAt).Sub(time.Time(*s.EndsAt).Truncate(24*time.Hour)))
+		return `{"silenceID":"` + maintenanceSilenceID + `"}`
+	},
+}, {
+	name: "MaintenanceDeleteExpire",
+	options: []telegram.BotOption{
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " 2021-01-01T00:00:00Z 2021-01-01T23:59:00Z every:1d job=backup",
+		},
+	}},
+	laterMessages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " delete 95d60967",
+		},
+	}},
+	laterDelay: 50 * time.Millisecond,
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^🔧 Maintenance window <code>95d60967</code> saved, next from ",
+	}, {
+		recipient: "123",
+		pattern:   "^🔧 The maintenance window <code>95d60967</code> starts (now|in 1 minute), I silenced ",
+	}, {
+		recipient: "123",
+		message:   "Maintenance window 95d60967 deleted and its silence expired.",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/maintenance 2021-01-01T00:00:00Z 2021-01-01T23:59:00Z every:1d job=backup\"",
+		"level=info msg=\"maintenance window saved\" id=95d60967 every=1d saved_by=123",
+		"level=info msg=\"maintenance window silenced\" id=95d60967 silence_id=" + maintenanceSilenceID,
+		"level=debug msg=\"message received\" text=\"/maintenance delete 95d60967\"",
+		"level=info msg=\"maintenance window deleted\" id=95d60967 deleted_by=123",
+		"level=info msg=\"maintenance window silence expired\" id=95d60967 silence_id=" + maintenanceSilenceID,
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		if r.Method == http.MethodDelete {
+			require.Equal(t, "/api/v2/silence/"+maintenanceSilenceID, r.URL.Path)
+			return "{}"
+		}
+		require.Equal(t, http.MethodPost, r.Method)
+		return `{"silenceID":"` + maintenanceSilenceID + `"}`
+	},
+}, {
+	// The Alertmanager doesn't know the silence anymore, the user is told it might still be active.
+	name: "MaintenanceDeleteExpireFailed",
+	options: []telegram.BotOption{
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " 2021-01-01T00:00:00Z 2021-01-01T23:59:00Z every:1d job=backup",
+		},
+	}},
+	laterMessages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMaintenance + " delete 95d60967",
+		},
+	}},
+	laterDelay: 50 * time.Millisecond,
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^🔧 Maintenance window <code>95d60967</code> saved, next from ",
+	}, {
+		recipient: "123",
+		pattern:   "^🔧 The maintenance window <code>95d60967</code> starts (now|in 1 minute), I silenced ",
+	}, {
+		recipient: "123",
+		pattern:   "^Maintenance window 95d60967 deleted, but I can't expire its silence " + maintenanceSilenceID + ", it stays active until \\d{4}-\\d{2}-\\d{2} 23:59 UTC.$",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/maintenance 2021-01-01T00:00:00Z 2021-01-01T23:59:00Z every:1d job=backup\"",
+		"level=info msg=\"maintenance window saved\" id=95d60967 every=1d saved_by=123",
+		"level=info msg=\"maintenance window silenced\" id=95d60967 silence_id=" + maintenanceSilenceID,
+		"level=debug msg=\"message received\" text=\"/maintenance delete 95d60967\"",
+		"level=info msg=\"maintenance window deleted\" id=95d60967 deleted_by=123",
+		"level=warn msg=\"failed to expire maintenance window silence\" id=95d60967 silence_id=" + maintenanceSilenceID + " err=\"unknown error (status 404): {}\"",
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		if r.Method == http.MethodDelete {
+			return ""
+		}
+		require.Equal(t, http.MethodPost, r.Method)
+		return `{"silenceID":"` + maintenanceSilenceID + `"}`
+	},
+}}
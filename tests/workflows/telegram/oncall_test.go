@@ -0,0 +1,163 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var oncallWorkflows = []workflow{{
+	name: "OnCallNoRotations",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandOnCall,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "There are no on-call rotations yet.\n/rotation",
+	}},
+	counter: map[string]uint{telegram.CommandOnCall: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/oncall",
+	},
+}, {
+	name: "RotationUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRotation + " ops 1w yesterday 123",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "Usage: /rotation <name> <length> <first handoff> <user-id>...\n" +
+			"For example: /rotation ops 1w 2021-03-01T09:00:00Z 123 456\n" +
+			"Delete a rotation with /rotation delete <name>.",
+	}},
+	counter: map[string]uint{telegram.CommandRotation: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/rotation ops 1w yesterday 123\"",
+	},
+}, {
+	name:       "RotationOnCall",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRotation + " ops 1w 2021-03-01T09:00:00Z 123",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRotation,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandOnCall,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Rotation ops saved, @elliot is on call now.",
+	}, {
+		recipient: "123",
+		pattern:   "^Rotations:\nops: every 1w from 2021-03-01T09:00:00Z: 123\n\nUsage: /rotation",
+	}, {
+		recipient: "123",
+		pattern:   "^ops: @elliot until \\d{4}-\\d{2}-\\d{2} 09:00 UTC, then @elliot$",
+	}},
+	counter: map[string]uint{telegram.CommandRotation: 2, telegram.CommandOnCall: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/rotation ops 1w 2021-03-01T09:00:00Z 123\"",
+		"level=info msg=\"rotation saved\" rotation=ops participants=1 saved_by=123",
+		"level=debug msg=\"message received\" text=/rotation",
+		"level=debug msg=\"message received\" text=/oncall",
+	},
+}, {
+	name: "RotationOverride",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRotation + " ops 1w 2021-03-01T09:00:00Z 123",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandOverride + " ops 222 2h",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandOnCall + " ops",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Rotation ops saved, 123 is on call now.",
+	}, {
+		recipient: "123",
+		pattern:   "^222 is on call for ops until \\d{4}-\\d{2}-\\d{2} \\d{2}:\\d{2} UTC\\.$",
+	}, {
+		recipient: "123",
+		pattern:   "^ops: 222 until \\d{4}-\\d{2}-\\d{2} \\d{2}:\\d{2} UTC, then 123$",
+	}},
+	counter: map[string]uint{telegram.CommandRotation: 1, telegram.CommandOverride: 1, telegram.CommandOnCall: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/rotation ops 1w 2021-03-01T09:00:00Z 123\"",
+		"level=info msg=\"rotation saved\" rotation=ops participants=1 saved_by=123",
+		"level=debug msg=\"message received\" text=\"/override ops 222 2h\"",
+		"level=info msg=\"on-call overridden\" rotation=ops user_id=222 overridden_by=123",
+		"level=debug msg=\"message received\" text=\"/oncall ops\"",
+	},
+}, {
+	name: "WebhookOnCall",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRotation + " ops 1w 2021-03-01T09:00:00Z 123",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Rotation ops saved, 123 is on call now.",
+	}, {
+		recipient: "123",
+		message:   ackNotification,
+	}},
+	counter: map[string]uint{telegram.CommandRotation: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/rotation ops 1w 2021-03-01T09:00:00Z 123\"",
+		"level=info msg=\"rotation saved\" rotation=ops participants=1 saved_by=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		w := webhookAck("firing")
+		w[0].ChatID = 0
+		w[0].OnCall = "ops"
+		return w
+	},
+}, {
+	name:     "WebhookOnCallUnknown",
+	messages: []telebot.Update{},
+	replies:  []reply{},
+	logs: []string{
+		"level=warn msg=\"webhook for unknown rotation\" rotation=ops",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		w := webhookAck("firing")
+		w[0].ChatID = 0
+		w[0].OnCall = "ops"
+		return w
+	},
+}}
@@ -0,0 +1,241 @@
+package telegram
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func alertmanagerStatusReceivers(t *testing.T, r *http.Request) string {
+	return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\n  webhook_configs:\n  - send_resolved: true\n    url: http://localhost:8080/webhooks/telegram/123\n- name: team-a\n- name: team.b"}}`
+}
+
+// alertmanagerStatusProxied has webhooks behind a reverse proxy, one of them in a url_file,
+// next to webhooks on another host and reaching the bot directly.
+func alertmanagerStatusProxied(t *testing.T, r *http.Request) string {
+	dir, err := ioutil.TempDir("", "alertmanager-bot")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	file := filepath.Join(dir, "url")
+	require.NoError(t, ioutil.WriteFile(file, []byte("https://proxy/bot/webhooks/topic/network\n"), 0600))
+
+	return fmt.Sprintf(`{"config":{"original":"route:\n  receiver: admin\nreceivers:\n`+
+		`- name: admin\n  webhook_configs:\n  - url: https://proxy/bot/webhooks/telegram/123\n`+
+		`- name: all\n  webhook_configs:\n  - url: https://proxy/bot/webhooks/telegram/broadcast\n`+
+		`- name: network\n  webhook_configs:\n  - url_file: %s\n`+
+		`- name: other\n  webhook_configs:\n  - url: https://proxy/other/webhooks/telegram/456\n`+
+		`- name: unknown\n  webhook_configs:\n  - url: https://proxy/bot/webhooks/telegram/unknown\n`+
+		`- name: elsewhere\n  webhook_configs:\n  - url: https://elsewhere/bot/webhooks/telegram/789\n`+
+		`- name: direct\n  webhook_configs:\n  - url: http://alertmanager-bot:8080/webhooks/telegram/321\n`+
+		`- name: missing\n  webhook_configs:\n  - url_file: /does/not/exist"}}`, file)
+}
+
+var receiverWorkflows = []workflow{{
+	name: "ReceiverConfig",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceiver,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This chat lists the alerts of admin, as its webhook is configured there.\nChange it with /receiver set <receiver>.",
+	}},
+	counter: map[string]uint{telegram.CommandReceiver: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/receiver",
+	},
+	alertmanagerStatus: alertmanagerStatusReceivers,
+}, {
+	name: "ReceiverUnknown",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceiver + " set team-c",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "The Alertmanager has no receiver called team-c.",
+	}},
+	counter: map[string]uint{telegram.CommandReceiver: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/receiver set team-c\"",
+	},
+	alertmanagerStatus: alertmanagerStatusReceivers,
+}, {
+	name: "ReceiverSet",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceiver + " set team-a team.b",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceiver,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlerts,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceiver + " unset",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This chat now lists the alerts of team-a, team.b.",
+	}, {
+		recipient: "123",
+		message:   "This chat lists the alerts of team-a, team.b.\nChange it with /receiver set <receiver>.",
+	}, {
+		recipient: "123",
+		message:   "No alerts right now! 🎉",
+	}, {
+		recipient: "123",
+		message:   "This chat now lists the alerts of the receivers sending to it again.",
+	}},
+	counter: map[string]uint{telegram.CommandReceiver: 3, telegram.CommandAlerts: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/receiver set team-a team.b\"",
+		"level=info msg=\"chat bound to receivers\" receivers=team-a,team.b chat_id=123 user_id=123",
+		"level=debug msg=\"message received\" text=/receiver",
+		"level=debug msg=\"message received\" text=/alerts",
+		"level=debug msg=\"message received\" text=\"/receiver unset\"",
+		"level=info msg=\"chat unbound from receivers\" chat_id=123 user_id=123",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, `team-a|team\.b`, r.URL.Query().Get("receiver"))
+		return `[]`
+	},
+	alertmanagerStatus: alertmanagerStatusReceivers,
+}, {
+	name: "AlertsAll",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlerts + " all",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🔥 <b>damn</b> 🔥\n<b>Labels:</b>\n    bot: alertmanager-bot\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandAlerts: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/alerts all\"",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		require.Empty(t, r.URL.Query().Get("receiver"))
+		return fmt.Sprintf(
+			`[{"labels":{"alertname":"damn","bot":"alertmanager-bot"},"startsAt":"%s"}]`,
+			time.Now().Add(-time.Hour).Format(time.RFC3339),
+		)
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin"}}`
+	},
+}, {
+	name: "Receivers",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceivers,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Receivers sending to this bot:\nChat 321: direct",
+	}},
+	counter: map[string]uint{telegram.CommandReceivers: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/receivers",
+	},
+	alertmanagerStatus: alertmanagerStatusProxied,
+}, {
+	name:    "ReceiversExternalURL",
+	options: []telegram.BotOption{telegram.WithExternalURL(&url.URL{Scheme: "https", Host: "proxy", Path: "/bot/"})},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceivers,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Receivers sending to this bot:\nChat 123: admin\nSubscribed chats bound with /receiver: all\nTopic network: network",
+	}},
+	counter: map[string]uint{telegram.CommandReceivers: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/receivers",
+	},
+	alertmanagerStatus: alertmanagerStatusProxied,
+}, {
+	name: "ReceiversNone",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReceivers,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "No receiver of the Alertmanager sends to this bot.\nIts webhooks need URLs like /webhooks/telegram/<chat-id>.",
+	}},
+	counter: map[string]uint{telegram.CommandReceivers: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/receivers",
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin"}}`
+	},
+}, {
+	name:       "AlertsBroadcastReceiverUnbound",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options:    []telegram.BotOption{telegram.WithExternalURL(&url.URL{Scheme: "https", Host: "proxy", Path: "/bot/"})},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlerts,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "No alerts right now! 🎉",
+	}},
+	counter: map[string]uint{telegram.CommandAlerts: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/alerts",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, "admin", r.URL.Query().Get("receiver"))
+		return `[]`
+	},
+	alertmanagerStatus: alertmanagerStatusProxied,
+}}
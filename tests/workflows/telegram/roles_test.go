@@ -0,0 +1,141 @@
+package telegram
+
+import (
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var rolesWorkflows = []workflow{{
+	name: "GrantList",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGrant,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Users with a role:\n123: admin\n\nUsage: /grant <user-id> <viewer|operator|admin>",
+	}},
+	counter: map[string]uint{telegram.CommandGrant: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/grant",
+	},
+}, {
+	name: "GrantUnknownRole",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGrant + " 222 superuser",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /grant <user-id> <viewer|operator|admin>",
+	}},
+	counter: map[string]uint{telegram.CommandGrant: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/grant 222 superuser\"",
+	},
+}, {
+	name: "GrantViewer",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGrant + " 222 viewer",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandHelp,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandChats,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "User 222 is now viewer.",
+	}, {
+		recipient: "222",
+		message:   strings.TrimSpace(telegram.ResponseHelp),
+	}, {
+		recipient: "222",
+		message:   "Sorry, you're not allowed to use this command.",
+	}},
+	counter: map[string]uint{
+		telegram.CommandGrant: 1,
+		telegram.CommandHelp:  1,
+	},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/grant 222 viewer\"",
+		"level=info msg=\"role granted\" user_id=222 role=viewer granted_by=123",
+		"level=debug msg=\"message received\" text=/help",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}, {
+	name: "GrantAndRevoke",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGrant + " 222 operator",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRevoke + " 222",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandHelp,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "User 222 is now operator.",
+	}, {
+		recipient: "123",
+		message:   "User 222 has no role anymore.",
+	}},
+	counter: map[string]uint{
+		telegram.CommandGrant:  1,
+		telegram.CommandRevoke: 1,
+	},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/grant 222 operator\"",
+		"level=info msg=\"role granted\" user_id=222 role=operator granted_by=123",
+		"level=debug msg=\"message received\" text=\"/revoke 222\"",
+		"level=info msg=\"role revoked\" user_id=222 revoked_by=123",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}, {
+	name: "RevokeCommandLineAdmin",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRevoke + " 123",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "User 123 is an admin given on the command line, I can't revoke that.",
+	}},
+	counter: map[string]uint{telegram.CommandRevoke: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/revoke 123\"",
+	},
+}}
@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func alertmanagerStatusRoutes(t *testing.T, r *http.Request) string {
+	return `{"config":{"original":"route:\n  receiver: admin\n  group_by: [alertname]\n  routes:\n` +
+		`  - receiver: pager\n    match:\n      severity: critical\n    continue: true\n    repeat_interval: 1h\n` +
+		`  - receiver: team-api\n    match_re:\n      job: api.*\n    group_by: ['...']\n` +
+		`receivers:\n- name: admin\n- name: pager\n- name: team-api"}}`
+}
+
+// alertmanagerStatusRoutesLong has a catch-all route and too many team routes to fit into a single message.
+func alertmanagerStatusRoutesLong(t *testing.T, r *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(`route:\n  receiver: admin\n  routes:\n  - receiver: fallback\n`)
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, `  - receiver: fallback\n    match:\n      team: team-%03d\n`, i)
+	}
+	sb.WriteString(`receivers:\n- name: admin\n- name: fallback`)
+	return `{"config":{"original":"` + sb.String() + `"}}`
+}
+
+var routesWorkflows = []workflow{{
+	name: "Routes",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Routing tree:</b>\n<pre>" +
+			"└── default-route  receiver: admin\n" +
+			"      group_by: [alertname]  wait: 30s  interval: 5m  repeat: 4h\n" +
+			"    ├── {severity=&#34;critical&#34;}  receiver: pager  continue: true\n" +
+			"    │     group_by: [alertname]  wait: 30s  interval: 5m  repeat: 1h\n" +
+			"    └── {job=~&#34;^(?:api.*)$&#34;}  receiver: team-api\n" +
+			"          group_by: [...]  wait: 30s  interval: 5m  repeat: 4h\n" +
+			"</pre>",
+	}},
+	counter: map[string]uint{telegram.CommandRoutes: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/routes",
+	},
+	alertmanagerStatus: alertmanagerStatusRoutes,
+}, {
+	name: "RoutesLong",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern: `(?s)^<b>Routing tree:</b>\n<pre>` +
+			`└── default-route  receiver: admin\n` +
+			`      group_by: \[\]  wait: 30s  interval: 5m  repeat: 4h\n` +
+			`    ├── receiver: fallback\n` +
+			`    │     group_by: \[\]  wait: 30s  interval: 5m  repeat: 4h\n` +
+			`    ├── {team=&#34;team-000&#34;}  receiver: fallback\n` +
+			`.*\n\[SNIP\]\n</pre>$`,
+	}},
+	counter: map[string]uint{telegram.CommandRoutes: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/routes",
+	},
+	alertmanagerStatus: alertmanagerStatusRoutesLong,
+}, {
+	name: "RoutesTest",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes + " test severity=critical job=api",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes + " test alertname=NodeDown",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Alerts with {job=\"api\", severity=\"critical\"} are sent to:\npager via {}/{severity=\"critical\"}\nteam-api via {}/{job=~\"^(?:api.*)$\"}",
+	}, {
+		recipient: "123",
+		message:   "Alerts with {alertname=\"NodeDown\"} are sent to:\nadmin via {}",
+	}},
+	counter: map[string]uint{telegram.CommandRoutes: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/routes test severity=critical job=api\"",
+		"level=debug msg=\"message received\" text=\"/routes test alertname=NodeDown\"",
+	},
+	alertmanagerStatus: alertmanagerStatusRoutes,
+}, {
+	name: "RoutesTestUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes + " test severity",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /routes test <label>=<value>...\nFor example: /routes test alertname=NodeDown severity=critical",
+	}},
+	counter: map[string]uint{telegram.CommandRoutes: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/routes test severity\"",
+	},
+	alertmanagerStatus: alertmanagerStatusRoutes,
+}}
@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func alertmanagerAlertsSilence(t *testing.T, r *http.Request) string {
+	startsAt := time.Now().Add(-time.Hour).Format(time.RFC3339Nano)
+	return fmt.Sprintf(`[`+
+		`{"fingerprint":"7a90bbdd1d39f61b","labels":{"alertname":"Watchdog","severity":"none"},"startsAt":"%[1]s","status":{"state":"active"}},`+
+		`{"fingerprint":"1b2c3d4e5f60718a","labels":{"alertname":"NodeDown","instance":"node-1","severity":"critical"},"startsAt":"%[1]s","status":{"state":"active"}},`+
+		`{"fingerprint":"2c3d4e5f60718a9b","labels":{"alertname":"NodeDown","instance":"node-2","severity":"critical"},"startsAt":"%[1]s","status":{"state":"active"}}`+
+		`]`, startsAt)
+}
+
+var silenceWorkflows = []workflow{{
+	name: "SilenceConfirmed",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilence + " 2h alertname=Watchdog Planned maintenance",
+		},
+	}, {
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: chatFromUser(admin)},
+			Data:    "\fsilence_confirm|1 yes",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🔕 Silence <code>{alertname=&#34;Watchdog&#34;}</code> for 2h?\nIt silences 1 alert:\n    Watchdog <code>7a90bbdd1d39f61b</code>",
+	}, {
+		recipient: "123",
+		message:   "🔕 @elliot silenced <code>{alertname=&#34;Watchdog&#34;}</code> for 2h with the silence <code>" + extendSilenceID + "</code>.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1, "button:silence_confirm": 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 2h alertname=Watchdog Planned maintenance\"",
+		"level=info msg=\"silence created\" silence_id=" + extendSilenceID + " matchers=\"{alertname=\\\"Watchdog\\\"}\" duration=2h user_id=123",
+	},
+	alertmanagerAlerts: alertmanagerAlertsSilence,
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, http.MethodPost, r.Method)
+
+		var s models.PostableSilence
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&s))
+		require.Equal(t, "@elliot", *s.CreatedBy)
+		require.Equal(t, "Planned maintenance", *s.Comment)
+		require.Len(t, s.Matchers, 1)
+		require.Equal(t, "Watchdog", *s.Matchers[0].Value)
+		require.Equal(t, 2*time.Hour, time.Time(*s.EndsAt).Sub(time.Time(*s.StartsAt)).Round(time.Minute))
+		return `{"silenceID":"` + extendSilenceID + `"}`
+	},
+}, {
+	name:    "SilenceBroadCancelled",
+	options: []telegram.BotOption{telegram.WithSilenceWarnThreshold(1)},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilence + " 1h severity=critical",
+		},
+	}, {
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: chatFromUser(admin)},
+			Data:    "\fsilence_confirm|1 no",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "🔕 Silence <code>{severity=&#34;critical&#34;}</code> for 1h?\n⚠️ These matchers are broad, they silence 2 alerts!\nIt silences 2 alerts:\n" +
+			"    NodeDown <code>1b2c3d4e5f60718a</code>\n    NodeDown <code>2c3d4e5f60718a9b</code>",
+	}, {
+		recipient: "123",
+		message:   "Alright, I won't silence <code>{severity=&#34;critical&#34;}</code>.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1, "button:silence_confirm": 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 1h severity=critical\"",
+	},
+	alertmanagerAlerts: alertmanagerAlertsSilence,
+}, {
+	name: "SilenceNoAlerts",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilence + " 1h alertname=~Disk.*",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🔕 Silence <code>{alertname=~&#34;Disk.*&#34;}</code> for 1h?\nIt doesn't silence any alert right now.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 1h alertname=~Disk.*\"",
+	},
+	alertmanagerAlerts: alertmanagerAlertsSilence,
+}, {
+	name: "SilenceUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilence + " 2h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "a duration and matchers are required\nUsage: /silence <duration> <matchers...> [comment]\nFor example: /silence 2h alertname=NodeDown instance=~node-1.* Replacing the disks",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 2h\"",
+	},
+}}
@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// alertmanagerSilence returns a silence of NodeDown on the instance in the given state ending in endsIn.
+func alertmanagerSilence(id, createdBy, instance, state string, endsIn time.Duration) string {
+	now := time.Now()
+	return fmt.Sprintf(`{"id":"%s","createdBy":"%s","comment":"","updatedAt":"%s","startsAt":"%s","endsAt":"%s",`+
+		`"matchers":[{"name":"alertname","value":"NodeDown","isRegex":false},{"name":"instance","value":"%s","isRegex":false}],`+
+		`"status":{"state":"%s"}}`,
+		id, createdBy,
+		now.Add(-2*time.Hour).Format(time.RFC3339Nano),
+		now.Add(-2*time.Hour).Format(time.RFC3339Nano),
+		now.Add(endsIn).Format(time.RFC3339Nano),
+		instance, state,
+	)
+}
+
+var silencesWorkflows = []workflow{{
+	name: "Silences",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilences,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>NodeDown</b> 🔕\n<code>instance=&#34;node-2&#34;</code>\n<b>ID:</b> <code>2</code>\n<b>Created by:</b> darlene\n<b>Started:</b> 2 hours ago\n<b>Ends:</b> in 3 hours\n\n" +
+			"<b>NodeDown</b> 🔕\n<code>instance=&#34;node-1&#34;</code>\n<b>ID:</b> <code>1</code>\n<b>Created by:</b> elliot\n<b>Started:</b> 2 hours ago\n<b>Ends:</b> in 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandSilences: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/silences",
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Empty(t, r.URL.Query()["filter"])
+		return "[" + strings.Join([]string{
+			alertmanagerSilence("1", "elliot", "node-1", "active", time.Hour),
+			alertmanagerSilence("2", "darlene", "node-2", "active", 3*time.Hour),
+			alertmanagerSilence("3", "elliot", "node-3", "expired", -time.Hour),
+		}, ",") + "]"
+	},
+}, {
+	name: "SilencesFilter",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilences + " expired by:elliot alertname=NodeDown",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "<b>NodeDown</b>\n<code>instance=&#34;node-3&#34;</code>\n<b>ID:</b> <code>3</code>\n<b>Created by:</b> elliot\n<b>Ended:</b> 1 hour ago\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandSilences: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silences expired by:elliot alertname=NodeDown\"",
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, []string{"alertname=NodeDown"}, r.URL.Query()["filter"])
+		return "[" + strings.Join([]string{
+			alertmanagerSilence("1", "elliot", "node-1", "active", time.Hour),
+			alertmanagerSilence("3", "elliot", "node-3", "expired", -time.Hour),
+			alertmanagerSilence("4", "darlene", "node-4", "expired", -time.Hour),
+		}, ",") + "]"
+	},
+}, {
+	name: "SilencesInvalidMatcher",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilences + " alertname",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^bad matcher format: alertname\nUsage: /silences \\[active\\] \\[pending\\] \\[expired\\] \\[by:<creator>\\] \\[matchers...\\]\n",
+	}},
+	counter: map[string]uint{telegram.CommandSilences: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silences alertname\"",
+	},
+}, {
+	name: "SilencesPages",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilences,
+		},
+	}, {
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: chatFromUser(admin)},
+			Data:    "\fsilences|1 1",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^<b>NodeDown</b> 🔕\n<code>instance=&#34;node-11&#34;</code>(.|\\n)*<code>instance=&#34;node-02&#34;</code>(.|\\n)*<i>Page 1 of 2, 12 silences</i>$",
+	}, {
+		recipient: "123",
+		pattern:   "^<b>NodeDown</b> 🔕\n<code>instance=&#34;node-01&#34;</code>(.|\\n)*<code>instance=&#34;node-00&#34;</code>(.|\\n)*<i>Page 2 of 2, 12 silences</i>$",
+	}},
+	counter: map[string]uint{telegram.CommandSilences: 1, "button:silences": 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/silences",
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		silences := make([]string, 0, 12)
+		for i := 0; i < 12; i++ {
+			silences = append(silences, alertmanagerSilence(
+				fmt.Sprint(i), "elliot", fmt.Sprintf("node-%02d", i), "active", time.Duration(i+1)*time.Hour,
+			))
+		}
+		return "[" + strings.Join(silences, ",") + "]"
+	},
+}}
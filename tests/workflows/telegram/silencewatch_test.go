@@ -0,0 +1,277 @@
+package telegram
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// alertmanagerSilencesPolled returns the silences of the poll with the same index, the last ones for all polls after.
+// The silences are rendered once per test run on the first poll, so that their durations are relative to the run.
+func alertmanagerSilencesPolled(polls ...func() []string) func(t *testing.T, r *http.Request) string {
+	var (
+		mu       sync.Mutex
+		i        = map[*testing.T]int{}
+		rendered = map[*testing.T][]string{}
+	)
+	return func(t *testing.T, r *http.Request) string {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v2/silence/") {
+			return alertmanagerSilence(extendSilenceID, "darlene", "node-1", "expired", -time.Minute)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if rendered[t] == nil {
+			for _, poll := range polls {
+				rendered[t] = append(rendered[t], "["+strings.Join(poll(), ",")+"]")
+			}
+		}
+		silences := rendered[t][len(polls)-1]
+		if i[t] < len(polls) {
+			silences = rendered[t][i[t]]
+		}
+		i[t]++
+		return silences
+	}
+}
+
+// alertmanagerSilencesCreated lists the silence created with the bot once it's posted
+// and the silence replacing it once it's extended.
+func alertmanagerSilencesCreated() func(t *testing.T, r *http.Request) string {
+	var (
+		mu     sync.Mutex
+		listed = map[*testing.T]string{}
+		posts  = map[*testing.T]int{}
+	)
+	ids := []string{extendSilenceID, extendedSilenceID}
+	return func(t *testing.T, r *http.Request) string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost:
+			id := ids[posts[t]]
+			posts[t]++
+			listed[t] = "[" + alertmanagerSilence(id, "@elliot", "node-1", "active", 10*time.Minute) + "]"
+			return `{"silenceID":"` + id + `"}`
+		case strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+			return alertmanagerSilence(extendSilenceID, "@elliot", "node-1", "active", 10*time.Minute)
+		case listed[t] == "":
+			return "[]"
+		}
+		return listed[t]
+	}
+}
+
+// extendedSilenceID is the ID of the silence replacing the one with extendSilenceID once it's extended.
+const extendedSilenceID = "0c0b2f3a-2f5e-4b2d-9a4b-5d1b9c2ab7c1"
+
+// silenceWatchGroup is subscribed to all alerts, but isn't bound to the receiver of the watched silences.
+var silenceWatchGroup = &telebot.Chat{ID: -1234, Type: telebot.ChatGroup}
+
+var silenceWatchWorkflows = []workflow{{
+	name:       "SilenceReminder",
+	subscribed: []*telebot.Chat{chatFromUser(admin), silenceWatchGroup},
+	options: []telegram.BotOption{
+		telegram.WithSilenceReminder(15 * time.Minute),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^⏰ The silence of <b>NodeDown</b> ends in 10 minutes.\n\n<b>NodeDown</b> 🔕\n<code>instance=&#34;node-1&#34;</code>\n<b>ID:</b> <code>" + extendSilenceID + "</code>",
+	}},
+	alertmanagerStatus: alertmanagerStatusReceivers,
+	alertmanagerSilences: alertmanagerSilencesPolled(
+		func() []string {
+			return []string{
+				alertmanagerSilence(extendSilenceID, "darlene", "node-1", "active", 10*time.Minute),
+				alertmanagerSilence(extendedSilenceID, "darlene", "node-2", "active", time.Hour),
+			}
+		},
+	),
+	logs: []string{
+		"level=info msg=\"reminded of expiring silence\" silence_id=" + extendSilenceID + " chat_id=0",
+	},
+}, {
+	name:       "SilenceAppearedVanished",
+	subscribed: []*telebot.Chat{chatFromUser(admin), silenceWatchGroup},
+	options: []telegram.BotOption{
+		telegram.WithSilenceReminder(15 * time.Minute),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^🔕 A new silence of <b>NodeDown</b> was created outside of this bot.\n\n<b>NodeDown</b> 🔕\n<code>instance=&#34;node-1&#34;</code>",
+	}, {
+		recipient: "123",
+		message:   "🔔 The silence <code>" + extendSilenceID + "</code> of <b>NodeDown</b> created outside of this bot isn't active anymore.",
+	}},
+	alertmanagerStatus: alertmanagerStatusReceivers,
+	alertmanagerSilences: alertmanagerSilencesPolled(
+		func() []string { return nil },
+		func() []string {
+			return []string{alertmanagerSilence(extendSilenceID, "darlene", "node-1", "active", time.Hour)}
+		},
+		func() []string { return nil },
+	),
+	logs: []string{
+		"level=info msg=\"silence appeared\" silence_id=" + extendSilenceID + " created_by=darlene",
+		"level=info msg=\"silence vanished\" silence_id=" + extendSilenceID,
+	},
+}, {
+	name:       "SilenceReminderCreator",
+	subscribed: []*telebot.Chat{chatFromUser(admin)},
+	options: []telegram.BotOption{
+		telegram.WithSilenceReminder(15 * time.Minute),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   silenceWatchGroup,
+			Text:   telegram.CommandSilence + " 10m alertname=NodeDown instance=node-1",
+		},
+	}, {
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: silenceWatchGroup},
+			Data:    "\fsilence_confirm|1 yes",
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		pattern:   "^🔕 Silence <code>.*</code> for 10m\\?",
+	}, {
+		recipient: "-1234",
+		pattern:   "^🔕 @elliot silenced <code>.*</code> for 10m with the silence <code>" + extendSilenceID + "</code>.$",
+	}, {
+		recipient: "-1234",
+		pattern:   "^⏰ The silence of <b>NodeDown</b> ends in 10 minutes.",
+	}, {
+		recipient: "123",
+		pattern:   "^⏰ The silence of <b>NodeDown</b> ends in 10 minutes.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1, "button:silence_confirm": 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 10m alertname=NodeDown instance=node-1\"",
+		"level=info msg=\"silence created\" silence_id=" + extendSilenceID + " matchers=\"{alertname=\\\"NodeDown\\\",instance=\\\"node-1\\\"}\" duration=10m user_id=123",
+		"level=info msg=\"reminded of expiring silence\" silence_id=" + extendSilenceID + " chat_id=-1234",
+	},
+	alertmanagerAlerts:   alertmanagerAlertsSilence,
+	alertmanagerStatus:   alertmanagerStatusReceivers,
+	alertmanagerSilences: alertmanagerSilencesCreated(),
+}, {
+	name: "SilenceReminderCreatorNotStarted",
+	options: []telegram.BotOption{
+		telegram.WithSilenceReminder(15 * time.Minute),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   silenceWatchGroup,
+			Text:   telegram.CommandSilence + " 10m alertname=NodeDown instance=node-1",
+		},
+	}, {
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: silenceWatchGroup},
+			Data:    "\fsilence_confirm|1 yes",
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		pattern:   "^🔕 Silence <code>.*</code> for 10m\\?",
+	}, {
+		recipient: "-1234",
+		pattern:   "^🔕 @elliot silenced <code>.*</code> for 10m with the silence <code>" + extendSilenceID + "</code>.$",
+	}, {
+		recipient: "-1234",
+		pattern:   "^⏰ The silence of <b>NodeDown</b> ends in 10 minutes.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1, "button:silence_confirm": 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 10m alertname=NodeDown instance=node-1\"",
+		"level=info msg=\"silence created\" silence_id=" + extendSilenceID + " matchers=\"{alertname=\\\"NodeDown\\\",instance=\\\"node-1\\\"}\" duration=10m user_id=123",
+		"level=info msg=\"reminded of expiring silence\" silence_id=" + extendSilenceID + " chat_id=-1234",
+	},
+	alertmanagerAlerts:   alertmanagerAlertsSilence,
+	alertmanagerStatus:   alertmanagerStatusReceivers,
+	alertmanagerSilences: alertmanagerSilencesCreated(),
+}, {
+	name: "SilenceExtendedWatched",
+	options: []telegram.BotOption{
+		telegram.WithSilenceReminder(5 * time.Minute),
+		telegram.WithSchedulerInterval(20 * time.Millisecond),
+	},
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   silenceWatchGroup,
+			Text:   telegram.CommandSilence + " 10m alertname=NodeDown instance=node-1",
+		},
+	}, {
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: silenceWatchGroup},
+			Data:    "\fsilence_confirm|1 yes",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   silenceWatchGroup,
+			Text:   telegram.CommandExtend + " " + extendSilenceID + " 1h",
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		pattern:   "^🔕 Silence <code>.*</code> for 10m\\?",
+	}, {
+		recipient: "-1234",
+		pattern:   "^🔕 @elliot silenced <code>.*</code> for 10m with the silence <code>" + extendSilenceID + "</code>.$",
+	}, {
+		recipient: "-1234",
+		pattern:   "^⏳ @elliot extended the silence <code>" + extendedSilenceID + "</code> by 1h",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1, "button:silence_confirm": 1, telegram.CommandExtend: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 10m alertname=NodeDown instance=node-1\"",
+		"level=info msg=\"silence created\" silence_id=" + extendSilenceID + " matchers=\"{alertname=\\\"NodeDown\\\",instance=\\\"node-1\\\"}\" duration=10m user_id=123",
+		"level=debug msg=\"message received\" text=\"/extend " + extendSilenceID + " 1h\"",
+		"level=info msg=\"silence extended\" silence_id=" + extendedSilenceID + " duration=1h user_id=123",
+	},
+	alertmanagerAlerts:   alertmanagerAlertsSilence,
+	alertmanagerStatus:   alertmanagerStatusReceivers,
+	alertmanagerSilences: alertmanagerSilencesCreated(),
+}, {
+	name: "SilenceLapse",
+	messages: []telebot.Update{{
+		Callback: &telebot.Callback{
+			ID:      "1",
+			Sender:  admin,
+			Message: &telebot.Message{ID: 1000, Chat: chatFromUser(admin)},
+			Data:    "\flapse|" + extendSilenceID,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		pattern:   "^⏰ The silence of <b>NodeDown</b> ends in (.|\\n)*\n\n💤 <b>Left to lapse by</b> @elliot$",
+	}},
+	counter: map[string]uint{"button:lapse": 1},
+	logs: []string{
+		"level=info msg=\"silence left to lapse\" silence_id=" + extendSilenceID + " user_id=123",
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, "/api/v2/silence/"+extendSilenceID, r.URL.Path)
+		return alertmanagerSilence(extendSilenceID, "darlene", "node-1", "active", 5*time.Minute)
+	},
+}}
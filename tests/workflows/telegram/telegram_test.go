@@ -7,10 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/docker/libkv/store"
 	"github.com/go-kit/kit/log"
 	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
 	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
@@ -27,9 +31,23 @@ type workflow struct {
 	logs     []string
 	counter  map[string]uint
 
-	webhooks           func() []alertmanager.TelegramWebhook
-	alertmanagerAlerts func(t *testing.T, r *http.Request) string
-	alertmanagerStatus func(t *testing.T, r *http.Request) string
+	webhooks func() []alertmanager.TelegramWebhook
+	// subscribed chats are added to the store before the workflow starts.
+	subscribed []*telebot.Chat
+	// webhooksFirst sends the webhooks before the messages, to reply to notifications.
+	webhooksFirst        bool
+	options              []telegram.BotOption
+	alertmanagerAlerts   func(t *testing.T, r *http.Request) string
+	alertmanagerStatus   func(t *testing.T, r *http.Request) string
+	alertmanagerSilences func(t *testing.T, r *http.Request) string
+	alertmanagerGroups   func(t *testing.T, r *http.Request) string
+	// failedSends is the number of messages to a recipient that fail to send, before sending works again.
+	failedSends map[string]int
+	// laterWebhooks and laterMessages are sent laterDelay after all other webhooks and messages,
+	// to let the scheduler run in between.
+	laterWebhooks func() []alertmanager.TelegramWebhook
+	laterMessages []telebot.Update
+	laterDelay    time.Duration
 }
 
 var (
@@ -121,6 +139,8 @@ func chatFromUser(user *telebot.User) *telebot.Chat {
 
 type reply struct {
 	recipient, message string
+	// pattern is matched instead of message for replies that aren't predictable, like times.
+	pattern string
 }
 
 type testStore struct {
@@ -133,6 +153,7 @@ func (t *testStore) List() ([]*telebot.Chat, error) {
 	for _, chat := range t.chats {
 		chats = append(chats, chat)
 	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i].ID < chats[j].ID })
 	return chats, nil
 }
 
@@ -156,6 +177,95 @@ func (t *testStore) Remove(_ *telebot.Chat) error {
 	return nil
 }
 
+// testKV is an in-memory libkv store for the bot's stores built on top of libkv.
+type testKV struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (t *testKV) Put(key string, value []byte, _ *store.WriteOptions) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.data == nil {
+		t.data = make(map[string][]byte)
+	}
+	t.data[key] = value
+	return nil
+}
+
+func (t *testKV) Get(key string) (*store.KVPair, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	value, ok := t.data[key]
+	if !ok {
+		return nil, store.ErrKeyNotFound
+	}
+	return &store.KVPair{Key: key, Value: value}, nil
+}
+
+func (t *testKV) Delete(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.data, key)
+	return nil
+}
+
+func (t *testKV) Exists(key string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.data[key]
+	return ok, nil
+}
+
+func (t *testKV) List(prefix string) ([]*store.KVPair, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var pairs []*store.KVPair
+	for key, value := range t.data {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, &store.KVPair{Key: key, Value: value})
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+func (t *testKV) DeleteTree(prefix string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.data {
+		if strings.HasPrefix(key, prefix) {
+			delete(t.data, key)
+		}
+	}
+	return nil
+}
+
+func (t *testKV) Watch(string, <-chan struct{}) (<-chan *store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+func (t *testKV) WatchTree(string, <-chan struct{}) (<-chan []*store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+func (t *testKV) NewLock(string, *store.LockOptions) (store.Locker, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+func (t *testKV) AtomicPut(string, []byte, *store.KVPair, *store.WriteOptions) (bool, *store.KVPair, error) {
+	return false, nil, store.ErrCallNotSupported
+}
+
+func (t *testKV) AtomicDelete(string, *store.KVPair) (bool, error) {
+	return false, store.ErrCallNotSupported
+}
+
+func (t *testKV) Close() {}
+
 type testCommandCounter struct {
 	counter map[string]uint
 }
@@ -166,8 +276,11 @@ func (c *testCommandCounter) Count(command string) {
 
 // wraps telebot to intercept sent messages.
 type testTelegram struct {
-	bot     *telebot.Bot
+	bot *telebot.Bot
+
+	mu      sync.Mutex
 	replies []reply
+	failed  map[string]int
 }
 
 func (t *testTelegram) Start() {
@@ -183,10 +296,41 @@ func (t *testTelegram) Send(to telebot.Recipient, message interface{}, _ ...inte
 	if !ok {
 		return nil, fmt.Errorf("message is not a string")
 	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failed[to.Recipient()] > 0 {
+		t.failed[to.Recipient()]--
+		return nil, fmt.Errorf("chat not found")
+	}
 	t.replies = append(t.replies, reply{recipient: to.Recipient(), message: text})
+
+	chatID, _ := strconv.ParseInt(to.Recipient(), 10, 64)
+	return &telebot.Message{ID: 1000 + len(t.replies), Chat: &telebot.Chat{ID: chatID}}, nil
+}
+
+func (t *testTelegram) Edit(msg telebot.Editable, message interface{}, _ ...interface{}) (*telebot.Message, error) {
+	text, ok := message.(string)
+	if !ok {
+		return nil, fmt.Errorf("message is not a string")
+	}
+	_, chatID := msg.MessageSig()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.replies = append(t.replies, reply{recipient: strconv.FormatInt(chatID, 10), message: text})
 	return nil, nil
 }
 
+// Respond records the callback response's text as a reply to the recipient "callback".
+func (t *testTelegram) Respond(_ *telebot.Callback, resp ...*telebot.CallbackResponse) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range resp {
+		t.replies = append(t.replies, reply{recipient: "callback", message: r.Text})
+	}
+	return nil
+}
+
 func (t *testTelegram) Notify(_ telebot.Recipient, _ telebot.ChatAction) error {
 	return nil // nop
 }
@@ -195,6 +339,10 @@ func (t *testTelegram) Handle(endpoint interface{}, handler interface{}) {
 	t.bot.Handle(endpoint, handler)
 }
 
+func (t *testTelegram) ChatMemberOf(_ *telebot.Chat, user *telebot.User) (*telebot.ChatMember, error) {
+	return &telebot.ChatMember{User: user, Role: telebot.Member}, nil
+}
+
 type testPoller struct {
 	updates chan telebot.Update
 	done    chan struct{}
@@ -214,6 +362,8 @@ func (t *testPoller) Poll(_ *telebot.Bot, updates chan telebot.Update, stop chan
 func TestWorkflows(t *testing.T) {
 	var testAlertmanagerAlerts func(t *testing.T, r *http.Request) string
 	var testAlertmanagerStatus func(t *testing.T, r *http.Request) string
+	var testAlertmanagerSilences func(t *testing.T, r *http.Request) string
+	var testAlertmanagerGroups func(t *testing.T, r *http.Request) string
 	var am *alertmanager.Client
 	{
 		m := http.NewServeMux()
@@ -225,6 +375,14 @@ func TestWorkflows(t *testing.T) {
 			w.Header().Set("Content-Type", "application/json")
 			_, _ = w.Write([]byte(data))
 		})
+		m.HandleFunc("/api/v2/alerts/groups", func(w http.ResponseWriter, r *http.Request) {
+			data := "[]"
+			if testAlertmanagerGroups != nil {
+				data = testAlertmanagerGroups(t, r)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(data))
+		})
 		m.HandleFunc("/api/v2/status", func(w http.ResponseWriter, r *http.Request) {
 			data := "{}"
 			if testAlertmanagerStatus != nil {
@@ -233,6 +391,27 @@ func TestWorkflows(t *testing.T) {
 			w.Header().Set("Content-Type", "application/json")
 			_, _ = w.Write([]byte(data))
 		})
+		m.HandleFunc("/api/v2/silences", func(w http.ResponseWriter, r *http.Request) {
+			data := "[]"
+			if testAlertmanagerSilences != nil {
+				data = testAlertmanagerSilences(t, r)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(data))
+		})
+		// A single silence is looked up by the same function, an empty response means it doesn't exist.
+		m.HandleFunc("/api/v2/silence/", func(w http.ResponseWriter, r *http.Request) {
+			data := ""
+			if testAlertmanagerSilences != nil {
+				data = testAlertmanagerSilences(t, r)
+			}
+			if data == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(data))
+		})
 
 		server := httptest.NewServer(m)
 		defer server.Close()
@@ -243,19 +422,44 @@ func TestWorkflows(t *testing.T) {
 		require.NoError(t, err)
 	}
 
+	// Append to a copy, so that running the test multiple times doesn't run the workflows multiple times.
+	workflows := append([]workflow(nil), workflows...)
+	workflows = append(workflows, ackWorkflows...)
+	workflows = append(workflows, alertWorkflows...)
 	workflows = append(workflows, alertsWorkflows...)
+	workflows = append(workflows, auditWorkflows...)
 	workflows = append(workflows, chatsWorkflows...)
+	workflows = append(workflows, dedupWorkflows...)
+	workflows = append(workflows, escalationWorkflows...)
+	workflows = append(workflows, extendWorkflows...)
+	workflows = append(workflows, flappingWorkflows...)
 	workflows = append(workflows, helpWorkflows...)
+	workflows = append(workflows, groupsWorkflows...)
+	workflows = append(workflows, historyWorkflows...)
 	workflows = append(workflows, idWorkflows...)
+	workflows = append(workflows, inhibitionsWorkflows...)
+	workflows = append(workflows, maintenanceWorkflows...)
+	workflows = append(workflows, oncallWorkflows...)
+	workflows = append(workflows, receiverWorkflows...)
+	workflows = append(workflows, rolesWorkflows...)
+	workflows = append(workflows, routesWorkflows...)
+	workflows = append(workflows, silenceWorkflows...)
+	workflows = append(workflows, silenceWatchWorkflows...)
+	workflows = append(workflows, silencesWorkflows...)
 	workflows = append(workflows, startWorkflows...)
 	workflows = append(workflows, stopWorkflows...)
 	workflows = append(workflows, statusWorkflows...)
+	workflows = append(workflows, topicsWorkflows...)
+	workflows = append(workflows, trustWorkflows...)
+	workflows = append(workflows, watchdogWorkflows...)
 	workflows = append(workflows, webhookWorkflows...)
 
 	for _, w := range workflows {
 		t.Run(w.name, func(t *testing.T) {
 			testAlertmanagerAlerts = w.alertmanagerAlerts
 			testAlertmanagerStatus = w.alertmanagerStatus
+			testAlertmanagerSilences = w.alertmanagerSilences
+			testAlertmanagerGroups = w.alertmanagerGroups
 
 			ctx, cancel := context.WithCancel(context.Background())
 			logs := &bytes.Buffer{}
@@ -265,51 +469,163 @@ func TestWorkflows(t *testing.T) {
 				done:    make(chan struct{}, 1),
 			}
 			tb, err := telebot.NewBot(telebot.Settings{
-				Offline: true,
-				Poller:  poller,
+				Offline:     true,
+				Poller:      poller,
+				Synchronous: true,
 			})
 			require.NoError(t, err)
 
 			testStore := &testStore{}
-			testTelegram := &testTelegram{bot: tb}
+			for _, chat := range w.subscribed {
+				require.NoError(t, testStore.Add(chat))
+			}
+			kv := &testKV{}
+			topicStore, err := telegram.NewTopicStore(kv, "telegram/topics")
+			require.NoError(t, err)
+			roleStore, err := telegram.NewRoleStore(kv, "telegram/roles")
+			require.NoError(t, err)
+			trustedStore, err := telegram.NewRoleStore(kv, "telegram/trusted")
+			require.NoError(t, err)
+			auditStore, err := telegram.NewAuditStore(kv, "telegram/audit")
+			require.NoError(t, err)
+			notificationStore, err := telegram.NewNotificationStore(kv, "telegram/notifications")
+			require.NoError(t, err)
+			rotationStore, err := telegram.NewRotationStore(kv, "telegram/rotations")
+			require.NoError(t, err)
+			receiverStore, err := telegram.NewReceiverStore(kv, "telegram/receivers")
+			require.NoError(t, err)
+			silenceStore, err := telegram.NewSilenceStore(kv, "telegram/silences")
+			require.NoError(t, err)
+			maintenanceStore, err := telegram.NewMaintenanceStore(kv, "telegram/maintenance")
+			require.NoError(t, err)
+			historyStore, err := telegram.NewHistoryStore(kv, "telegram/history")
+			require.NoError(t, err)
+			testTelegram := &testTelegram{bot: tb, failed: map[string]int{}}
+			for recipient, n := range w.failedSends {
+				testTelegram.failed[recipient] = n
+			}
 			counter := testCommandCounter{counter: map[string]uint{}}
 
-			bot, err := telegram.NewBotWithTelegram(testStore, testTelegram, admin.ID,
-				telegram.WithLogger(log.NewLogfmtLogger(logs)),
+			// The bot signals every webhook handled, and handles poll answers after all messages sent before,
+			// so that the workflow can wait for them instead of sleeping.
+			webhooksHandled := make(chan struct{}, 100)
+			messagesHandled := make(chan struct{}, 1)
+			tb.Handle(telebot.OnPollAnswer, func(*telebot.PollAnswer) { messagesHandled <- struct{}{} })
+
+			options := []telegram.BotOption{
+				telegram.WithLogger(log.NewLogfmtLogger(log.NewSyncWriter(logs))),
 				telegram.WithCommandEvent(counter.Count),
+				telegram.WithWebhookEvent(func() { webhooksHandled <- struct{}{} }),
 				telegram.WithAlertmanager(am),
 				telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl"),
 				telegram.WithStartTime(time.Now().Add(-time.Minute)),
 				telegram.WithRevision("bot"),
-			)
+				telegram.WithTopics(topicStore, "network", "db"),
+				telegram.WithRoles(roleStore),
+				telegram.WithTrustedChats(trustedStore, time.Minute),
+				telegram.WithAuditLog(auditStore, 0),
+				telegram.WithNotifications(notificationStore),
+				telegram.WithRotations(rotationStore),
+				telegram.WithReceivers(receiverStore),
+				telegram.WithSilences(silenceStore),
+				telegram.WithMaintenance(maintenanceStore),
+				telegram.WithHistory(historyStore, 0),
+			}
+			options = append(options, w.options...)
+
+			bot, err := telegram.NewBotWithTelegram(testStore, testTelegram, admin.ID, options...)
 			require.NoError(t, err)
 
 			webhooks := make(chan alertmanager.TelegramWebhook, 10)
 
 			// Run the bot in the background and tests in foreground.
+			done := make(chan struct{})
 			go func(ctx context.Context) {
+				defer close(done)
 				require.NoError(t, bot.Run(ctx, webhooks))
 			}(ctx)
-
-			for i, update := range w.messages {
-				update.ID = i
-				update.Message.ID = i
-				poller.updates <- update
-				time.Sleep(time.Millisecond)
+			// Stop the bot before the next workflow, so that its scheduler doesn't query the next workflow's Alertmanager.
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			// waitHandled fails the workflow if the bot doesn't handle something within a second.
+			waitHandled := func(handled <-chan struct{}, what string) {
+				select {
+				case <-handled:
+				case <-time.After(time.Second):
+					require.FailNow(t, "bot didn't handle the "+what)
+				}
 			}
-
-			if w.webhooks != nil {
-				for _, webhook := range w.webhooks() {
+			sendWebhooks := func(send func() []alertmanager.TelegramWebhook) {
+				if send == nil {
+					return
+				}
+				sent := send()
+				for _, webhook := range sent {
 					webhooks <- webhook
 				}
+				for range sent {
+					waitHandled(webhooksHandled, "webhooks")
+				}
+			}
+			// sendMessages sends the updates numbered from first and waits until the bot handled them.
+			sendMessages := func(updates []telebot.Update, first int) {
+				if len(updates) == 0 {
+					return
+				}
+				for i, update := range updates {
+					update.ID = first + i
+					if update.Message != nil {
+						update.Message.ID = first + i
+					}
+					if update.Callback != nil {
+						// telebot strips the button's unique prefix off the callback's data.
+						callback := *update.Callback
+						update.Callback = &callback
+					}
+					poller.updates <- update
+					time.Sleep(time.Millisecond)
+				}
+				poller.updates <- telebot.Update{PollAnswer: &telebot.PollAnswer{}}
+				waitHandled(messagesHandled, "messages")
+			}
+
+			if w.webhooksFirst {
+				sendWebhooks(w.webhooks)
+			}
+
+			// Let the bot handle the messages, like subscribing chats, before it receives the webhooks.
+			sendMessages(w.messages, 0)
+
+			if !w.webhooksFirst {
+				sendWebhooks(w.webhooks)
+			}
+
+			if w.laterWebhooks != nil || w.laterMessages != nil {
+				time.Sleep(w.laterDelay)
+				sendWebhooks(w.laterWebhooks)
+				sendMessages(w.laterMessages, len(w.messages))
 			}
 
 			// TODO: Don't sleep but block somehow different
 			time.Sleep(100 * time.Millisecond)
 
+			// Stop the bot before reading what it did, so that its scheduler doesn't log or reply anymore.
+			cancel()
+			<-done
+
+			testTelegram.mu.Lock()
+			defer testTelegram.mu.Unlock()
+
 			require.Len(t, testTelegram.replies, len(w.replies))
 			for i, reply := range w.replies {
 				require.Equal(t, reply.recipient, testTelegram.replies[i].recipient)
+				if reply.pattern != "" {
+					require.Regexp(t, reply.pattern, strings.TrimSpace(testTelegram.replies[i].message))
+					continue
+				}
 				require.Equal(t, reply.message, strings.TrimSpace(testTelegram.replies[i].message))
 			}
 
@@ -324,8 +640,6 @@ func TestWorkflows(t *testing.T) {
 			for command, count := range counter.counter {
 				require.Equal(t, w.counter[command], count)
 			}
-
-			cancel()
 		})
 	}
 }
@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var topicsWorkflows = []workflow{{
+	name: "SubscribeList",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSubscribe,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Available topics:\n▫️ db\n▫️ network\n\nUse /subscribe <topic> or /unsubscribe <topic>.",
+	}},
+	counter: map[string]uint{telegram.CommandSubscribe: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/subscribe",
+	},
+}, {
+	name: "SubscribeUnknown",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSubscribe + " payments",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "There is no topic called payments.\n/subscribe",
+	}},
+	counter: map[string]uint{telegram.CommandSubscribe: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/subscribe payments\"",
+	},
+}, {
+	name: "SubscribeAndUnsubscribe",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSubscribe + " db",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSubscribe,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandUnsubscribe + " db",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This chat is now subscribed to db.",
+	}, {
+		recipient: "123",
+		message:   "Available topics:\n✅ db\n▫️ network\n\nUse /subscribe <topic> or /unsubscribe <topic>.",
+	}, {
+		recipient: "123",
+		message:   "This chat is now unsubscribed from db.",
+	}},
+	counter: map[string]uint{
+		telegram.CommandSubscribe:   2,
+		telegram.CommandUnsubscribe: 1,
+	},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/subscribe db\"",
+		"level=info msg=\"chat subscribed to topic\" topic=db user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=/subscribe",
+		"level=debug msg=\"message received\" text=\"/unsubscribe db\"",
+		"level=info msg=\"chat unsubscribed from topic\" topic=db user_id=123 chat_id=123",
+	},
+}, {
+	name: "WebhookTopicSubscriber",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandSubscribe + " db",
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "This chat is now subscribed to db.",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandSubscribe: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/subscribe db\"",
+		"level=info msg=\"chat subscribed to topic\" topic=db user_id=123 chat_id=-1234",
+		"level=warn msg=\"no chats are subscribed to topic\" topic=network",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{
+			{Topic: "db", Message: webhookFiring},
+			{Topic: "network", Message: webhookFiring},
+		}
+	},
+}}
@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var trustWorkflows = []workflow{{
+	name: "TrustPrivate",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandTrust,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Only group chats can be trusted.",
+	}},
+	counter: map[string]uint{telegram.CommandTrust: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/trust",
+	},
+}, {
+	name: "TrustGroup",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandStatus,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandTrust + " viewer",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandChats,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandHelp,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandHelp,
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "All members of this chat are now viewer.",
+	}, {
+		recipient: "-1234",
+		message:   "Sorry, you're not allowed to use this command.",
+	}, {
+		recipient: "-1234",
+		message:   strings.TrimSpace(telegram.ResponseHelp),
+	}},
+	counter: map[string]uint{
+		telegram.CommandTrust: 1,
+		telegram.CommandHelp:  1,
+	},
+	logs: []string{
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+		"level=debug msg=\"message received\" text=\"/trust viewer\"",
+		"level=info msg=\"chat trusted\" chat_id=-1234 role=viewer trusted_by=123",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+		"level=debug msg=\"message received\" text=/help",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}, {
+	name: "TrustAdmin",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandTrust + " admin",
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Usage: /trust <viewer|operator>\nAdmins can only be granted one by one with /grant.",
+	}},
+	counter: map[string]uint{telegram.CommandTrust: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/trust admin\"",
+	},
+}, {
+	name: "Untrust",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandTrust,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandUntrust,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   telegram.CommandChats,
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "All members of this chat are now viewer.",
+	}, {
+		recipient: "-1234",
+		message:   "Members of this chat don't get a role from it anymore.",
+	}},
+	counter: map[string]uint{
+		telegram.CommandTrust:   1,
+		telegram.CommandUntrust: 1,
+	},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/trust",
+		"level=info msg=\"chat trusted\" chat_id=-1234 role=viewer trusted_by=123",
+		"level=debug msg=\"message received\" text=/untrust",
+		"level=info msg=\"chat untrusted\" chat_id=-1234 untrusted_by=123",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}}
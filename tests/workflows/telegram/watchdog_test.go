@@ -0,0 +1,64 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+)
+
+func webhookWatchdog(alertname string) []alertmanager.TelegramWebhook {
+	return []alertmanager.TelegramWebhook{{
+		Watchdog: true,
+		Message: webhook.Message{
+			Data: &template.Data{
+				Receiver: "watchdog",
+				Status:   "firing",
+				Alerts: template.Alerts{{
+					Status:      "firing",
+					Labels:      template.KV{"alertname": alertname},
+					StartsAt:    time.Now().Add(-time.Hour),
+					Fingerprint: "1f2e3d4c5b6a7988",
+				}},
+				GroupLabels:  template.KV{"alertname": alertname},
+				CommonLabels: template.KV{"alertname": alertname},
+			},
+			Version:  "4",
+			GroupKey: `{}:{alertname="` + alertname + `"}`,
+		},
+	}}
+}
+
+var watchdogWorkflows = []workflow{{
+	name: "Watchdog",
+	options: []telegram.BotOption{
+		telegram.WithWatchdog("Watchdog", time.Hour),
+		telegram.WithSchedulerInterval(10 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookWatchdog("Watchdog")
+	},
+	replies: []reply{},
+	logs: []string{
+		"level=debug msg=\"watchdog alert arrived\" alert=Watchdog",
+	},
+}, {
+	name: "WatchdogMissing",
+	options: []telegram.BotOption{
+		telegram.WithWatchdog("Watchdog", 40*time.Millisecond),
+		telegram.WithSchedulerInterval(10 * time.Millisecond),
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return webhookWatchdog("fire")
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🐶 The Watchdog alert didn't arrive for 40ms, the alerting pipeline might be broken!",
+	}},
+	logs: []string{
+		"level=warn msg=\"watchdog webhook without the watchdog alert\" alert=Watchdog",
+		"level=warn msg=\"watchdog alert missing\" alert=Watchdog",
+	},
+}}
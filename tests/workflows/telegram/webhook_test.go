@@ -1,6 +1,8 @@
 package telegram
 
 import (
+	"net/http"
+	"testing"
 	"time"
 
 	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
@@ -114,4 +116,92 @@ var webhookWorkflows = []workflow{{
 		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
 		return []alertmanager.TelegramWebhook{{ChatID: int64(-1234), Message: webhookFiring}}
 	},
+}, {
+	name: "WebhookBroadcast",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -5678,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandReceiver + " set telegram",
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-5678",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "This chat now lists the alerts of telegram.",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 2, telegram.CommandReceiver: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-5678",
+		"level=debug msg=\"message received\" text=\"/receiver set telegram\"",
+		"level=info msg=\"chat bound to receivers\" receivers=telegram chat_id=-1234 user_id=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{{Broadcast: true, Message: webhookFiring}}
+	},
+	alertmanagerStatus: alertmanagerStatusBroadcast,
+}, {
+	name: "WebhookBroadcastUnbound",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=warn msg=\"no subscribed chats are bound to the broadcast receiver\" receiver=telegram",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{{Broadcast: true, Message: webhookFiring}}
+	},
 }}
+
+// alertmanagerStatusBroadcast has a receiver sending to the broadcast webhook.
+func alertmanagerStatusBroadcast(t *testing.T, r *http.Request) string {
+	return `{"config":{"original":"route:\n  receiver: telegram\nreceivers:\n- name: telegram\n  webhook_configs:\n  - url: http://localhost:8080/webhooks/telegram/broadcast"}}`
+}